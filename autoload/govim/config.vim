@@ -146,6 +146,154 @@ function! s:validExperimentalWorkaroundCompleteoptLongest(v)
   return s:validBool(a:v)
 endfunction
 
+function! s:validSignatureHelp(v)
+  return s:validBool(a:v)
+endfunction
+
+function! s:validExperimentalSignatureHelpPopupOptions(v)
+  return s:validExperimentalMouseTriggeredHoverPopupOptions(a:v)
+endfunction
+
+function! s:validCodeLens(v)
+  return s:validBool(a:v)
+endfunction
+
+function! s:validProgressPopup(v)
+  return s:validBool(a:v)
+endfunction
+
+function! s:validShowMessageRequestTimeout(v)
+  if type(a:v) != 1
+    return [v:false, "must be of type string"]
+  endif
+  " A non-negative Go time.Duration, e.g. "30s" or "1m30s"
+  if a:v !~# '^\%(0\|\%(\%(\d\+\%(\.\d*\)\?\|\.\d\+\)\%(ns\|us\|µs\|ms\|s\|m\|h\)\)\+\)$'
+    return [v:false, "must be a duration, e.g. \"30s\""]
+  endif
+  return [v:true, ""]
+endfunction
+
+function! s:validCompletionSnippets(v)
+  return s:validBool(a:v)
+endfunction
+
+function! s:validCompletionAsync(v)
+  return s:validBool(a:v)
+endfunction
+
+function! s:validGoplsCrashPolicy(v)
+  let valid = ["exit", "restart"]
+  if index(valid, a:v) < 0
+    return [v:false, "must be one of: ".string(valid)]
+  endif
+  return [v:true, ""]
+endfunction
+
+function! s:validGoToMultipleLocations(v)
+  let valid = ["popup", "loclist"]
+  if index(valid, a:v) < 0
+    return [v:false, "must be one of: ".string(valid)]
+  endif
+  return [v:true, ""]
+endfunction
+
+function! s:validRenamePreview(v)
+  return s:validBool(a:v)
+endfunction
+
+function! s:validMultiFileEditStrategy(v)
+  let valid = ["bufload", "split", "tab"]
+  if index(valid, a:v) < 0
+    return [v:false, "must be one of: ".string(valid)]
+  endif
+  return [v:true, ""]
+endfunction
+
+function! s:validFoldingRanges(v)
+  return s:validBool(a:v)
+endfunction
+
+function! s:validFoldingRangeKinds(v)
+  if type(a:v) != 3
+    return [v:false, "must be a list"]
+  endif
+  let valid = ["imports", "comment", "function", "composite", "other"]
+  for k in a:v
+    if index(valid, k) < 0
+      return [v:false, "elements must be one of: ".string(valid)]
+    endif
+  endfor
+  return [v:true, ""]
+endfunction
+
+function! s:validSemanticHighlighting(v)
+  return s:validBool(a:v)
+endfunction
+
+function! s:validSeverity(v)
+  let valid = ["error", "warning", "info", "hint"]
+  if index(valid, a:v) < 0
+    return [v:false, "must be one of: ".string(valid)]
+  endif
+  return [v:true, ""]
+endfunction
+
+function! s:validStringList(v)
+  if type(a:v) != 3
+    return [v:false, "must be a list"]
+  endif
+  for s in a:v
+    if type(s) != 1
+      return [v:false, "elements must be of type string"]
+    endif
+  endfor
+  return [v:true, ""]
+endfunction
+
+function! s:validDiagnosticsQuickfixSeverity(v)
+  return s:validSeverity(a:v)
+endfunction
+
+function! s:validDiagnosticsSignsSeverity(v)
+  return s:validSeverity(a:v)
+endfunction
+
+function! s:validDiagnosticsHighlightSeverity(v)
+  return s:validSeverity(a:v)
+endfunction
+
+function! s:validDiagnosticsHoverSeverity(v)
+  return s:validSeverity(a:v)
+endfunction
+
+function! s:validDiagnosticsIncludeSources(v)
+  return s:validStringList(a:v)
+endfunction
+
+function! s:validDiagnosticsExcludeSources(v)
+  return s:validStringList(a:v)
+endfunction
+
+function! s:validDiagnosticsExcludePaths(v)
+  return s:validStringList(a:v)
+endfunction
+
+function! s:validDiagnosticsList(v)
+  let valid = ["quickfix", "loclist"]
+  if index(valid, a:v) < 0
+    return [v:false, "must be one of: ".string(valid)]
+  endif
+  return [v:true, ""]
+endfunction
+
+function! s:validInlineDiagnostics(v)
+  return s:validBool(a:v)
+endfunction
+
+function! s:validDiagnosticsInlineSeverity(v)
+  return s:validSeverity(a:v)
+endfunction
+
 let s:validators = {
       \ "FormatOnSave": function("s:validFormatOnSave"),
       \ "QuickfixAutoDiagnostics": function("s:validQuickfixAutoDiagnostics"),
@@ -165,4 +313,28 @@ let s:validators = {
       \ "ExperimentalMouseTriggeredHoverPopupOptions": function("s:validExperimentalMouseTriggeredHoverPopupOptions"),
       \ "ExperimentalCursorTriggeredHoverPopupOptions": function("s:validExperimentalCursorTriggeredHoverPopupOptions"),
       \ "ExperimentalWorkaroundCompleteoptLongest": function("s:validExperimentalWorkaroundCompleteoptLongest"),
+      \ "SignatureHelp": function("s:validSignatureHelp"),
+      \ "ExperimentalSignatureHelpPopupOptions": function("s:validExperimentalSignatureHelpPopupOptions"),
+      \ "CodeLens": function("s:validCodeLens"),
+      \ "ProgressPopup": function("s:validProgressPopup"),
+      \ "ShowMessageRequestTimeout": function("s:validShowMessageRequestTimeout"),
+      \ "GoplsCrashPolicy": function("s:validGoplsCrashPolicy"),
+      \ "CompletionSnippets": function("s:validCompletionSnippets"),
+      \ "CompletionAsync": function("s:validCompletionAsync"),
+      \ "GoToMultipleLocations": function("s:validGoToMultipleLocations"),
+      \ "RenamePreview": function("s:validRenamePreview"),
+      \ "MultiFileEditStrategy": function("s:validMultiFileEditStrategy"),
+      \ "FoldingRanges": function("s:validFoldingRanges"),
+      \ "FoldingRangeKinds": function("s:validFoldingRangeKinds"),
+      \ "SemanticHighlighting": function("s:validSemanticHighlighting"),
+      \ "DiagnosticsQuickfixSeverity": function("s:validDiagnosticsQuickfixSeverity"),
+      \ "DiagnosticsSignsSeverity": function("s:validDiagnosticsSignsSeverity"),
+      \ "DiagnosticsHighlightSeverity": function("s:validDiagnosticsHighlightSeverity"),
+      \ "DiagnosticsHoverSeverity": function("s:validDiagnosticsHoverSeverity"),
+      \ "DiagnosticsIncludeSources": function("s:validDiagnosticsIncludeSources"),
+      \ "DiagnosticsExcludeSources": function("s:validDiagnosticsExcludeSources"),
+      \ "DiagnosticsExcludePaths": function("s:validDiagnosticsExcludePaths"),
+      \ "DiagnosticsList": function("s:validDiagnosticsList"),
+      \ "InlineDiagnostics": function("s:validInlineDiagnostics"),
+      \ "DiagnosticsInlineSeverity": function("s:validDiagnosticsInlineSeverity"),
       \ }
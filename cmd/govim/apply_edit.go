@@ -0,0 +1,323 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/govim/govim"
+	"github.com/govim/govim/cmd/govim/internal/golang_org_x_tools/jsonrpc2"
+	"github.com/govim/govim/cmd/govim/internal/golang_org_x_tools/lsp/protocol"
+	"github.com/govim/govim/cmd/govim/internal/golang_org_x_tools/span"
+	"github.com/kr/pretty"
+)
+
+// applyWorkspaceEditParams mirrors protocol.ApplyWorkspaceEditParams, but uses
+// our own workspaceEdit type (see below)
+type applyWorkspaceEditParams struct {
+	Label string        `json:"label,omitempty"`
+	Edit  workspaceEdit `json:"edit"`
+}
+
+// workspaceEdit mirrors protocol.WorkspaceEdit. The generated protocol type
+// declares DocumentChanges as []protocol.TextDocumentEdit, which silently
+// drops any create, rename or delete file operations sent by gopls. Hence we
+// decode workspace/applyEdit requests into this type instead.
+type workspaceEdit struct {
+	Changes         map[string][]protocol.TextEdit `json:"changes,omitempty"`
+	DocumentChanges []documentChange               `json:"documentChanges,omitempty"`
+}
+
+// documentChange is a single entry in workspaceEdit.DocumentChanges. Kind is
+// empty for a text document edit, otherwise it is one of the
+// protocol.ResourceOperationKind values.
+type documentChange struct {
+	Kind string `json:"kind,omitempty"`
+
+	// TextDocument and Edits are set for a text document edit
+	TextDocument protocol.VersionedTextDocumentIdentifier `json:"textDocument"`
+	Edits        []protocol.TextEdit                      `json:"edits,omitempty"`
+
+	// URI is set for create and delete operations
+	URI protocol.DocumentURI `json:"uri,omitempty"`
+
+	// OldURI and NewURI are set for rename operations
+	OldURI protocol.DocumentURI `json:"oldUri,omitempty"`
+	NewURI protocol.DocumentURI `json:"newUri,omitempty"`
+
+	Options resourceOperationOptions `json:"options,omitempty"`
+}
+
+// resourceOperationOptions is the union of protocol.CreateFileOptions,
+// protocol.RenameFileOptions and protocol.DeleteFileOptions
+type resourceOperationOptions struct {
+	Overwrite         bool `json:"overwrite,omitempty"`
+	IgnoreIfExists    bool `json:"ignoreIfExists,omitempty"`
+	IgnoreIfNotExists bool `json:"ignoreIfNotExists,omitempty"`
+	Recursive         bool `json:"recursive,omitempty"`
+}
+
+// toWorkspaceEdit converts a protocol.WorkspaceEdit, which by definition can
+// only contain text document edits, to a workspaceEdit
+func toWorkspaceEdit(e protocol.WorkspaceEdit) workspaceEdit {
+	res := workspaceEdit{
+		Changes: e.Changes,
+	}
+	for _, c := range e.DocumentChanges {
+		res.DocumentChanges = append(res.DocumentChanges, documentChange{
+			TextDocument: c.TextDocument,
+			Edits:        c.Edits,
+		})
+	}
+	return res
+}
+
+// applyEditHandler wraps handler, intercepting workspace/applyEdit requests
+// so that we can decode the params into an applyWorkspaceEditParams (see the
+// comment on workspaceEdit for why)
+func (g *govimplugin) applyEditHandler(handler jsonrpc2.Handler) jsonrpc2.Handler {
+	return func(ctx context.Context, r *jsonrpc2.Request) error {
+		if r.Method != "workspace/applyEdit" || r.Params == nil {
+			return handler(ctx, r)
+		}
+		var params applyWorkspaceEditParams
+		if err := json.Unmarshal(*r.Params, &params); err != nil {
+			return r.Reply(ctx, nil, jsonrpc2.NewErrorf(jsonrpc2.CodeParseError, "%v", err))
+		}
+		resp, err := g.applyEdit(&params)
+		return r.Reply(ctx, resp, err)
+	}
+}
+
+// applyEdit applies params on the Vim "thread" and blocks until the edit has
+// been applied (or has failed to apply).
+//
+// Because this blocks the calling gopls request, a call to gopls that might
+// result in a workspace/applyEdit request (e.g. ExecuteCommand) must not be
+// made synchronously from the Vim "thread", else we deadlock.
+func (g *govimplugin) applyEdit(params *applyWorkspaceEditParams) (*protocol.ApplyWorkspaceEditResponse, error) {
+	defer absorbShutdownErr()
+	g.logGoplsClientf("ApplyEdit callback: %v", pretty.Sprint(params))
+
+	var applyErr error
+	done, err := g.Schedule(func(govim.Govim) error {
+		applyErr = g.vimstate.applyWorkspaceEdit(params.Edit)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to schedule ApplyEdit: %v", err)
+	}
+	select {
+	case <-done:
+	case <-g.inShutdown:
+		return nil, govim.ErrShuttingDown
+	}
+
+	res := &protocol.ApplyWorkspaceEditResponse{
+		Applied: applyErr == nil,
+	}
+	if applyErr != nil {
+		res.FailureReason = applyErr.Error()
+		g.Logf("failed to apply workspace edit %q: %v", params.Label, applyErr)
+	}
+	g.logGoplsClientf("ApplyEdit response: %v", pretty.Sprint(res))
+	return res, nil
+}
+
+// applyWorkspaceEdit applies the changes in e in order. Consecutive text
+// document edits are applied together via applyMultiBufTextedits. Resource
+// operations are applied to the file system in between, taking care of any
+// Vim buffers that correspond to the files being changed.
+func (v *vimstate) applyWorkspaceEdit(e workspaceEdit) error {
+	// Only the changes to the contents of buffers can be undone, but they
+	// should be undone together
+	defer v.startEditTransaction()()
+
+	var pending []protocol.TextDocumentEdit
+	pendingURIs := make(map[protocol.DocumentURI]bool)
+	flush := func() error {
+		if len(pending) == 0 {
+			return nil
+		}
+		toApply := pending
+		pending = nil
+		pendingURIs = make(map[protocol.DocumentURI]bool)
+		return v.applyMultiBufTextedits(nil, toApply)
+	}
+	addPending := func(c protocol.TextDocumentEdit) error {
+		// applyMultiBufTextedits handles at most one edit per file
+		if pendingURIs[c.TextDocument.URI] {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+		pending = append(pending, c)
+		pendingURIs[c.TextDocument.URI] = true
+		return nil
+	}
+
+	// The LSP spec says changes are ignored if documentChanges are set
+	if len(e.DocumentChanges) == 0 {
+		var uris []string
+		for uri := range e.Changes {
+			uris = append(uris, uri)
+		}
+		// So that we have reproducible behaviour
+		sort.Strings(uris)
+		for _, uri := range uris {
+			var c protocol.TextDocumentEdit
+			c.TextDocument.URI = protocol.DocumentURI(uri)
+			c.Edits = e.Changes[uri]
+			pending = append(pending, c)
+		}
+		return flush()
+	}
+
+	for i, c := range e.DocumentChanges {
+		var err error
+		switch protocol.ResourceOperationKind(c.Kind) {
+		case "":
+			err = addPending(protocol.TextDocumentEdit{
+				TextDocument: c.TextDocument,
+				Edits:        c.Edits,
+			})
+		case protocol.Create:
+			if err = flush(); err == nil {
+				err = v.createFile(span.URI(c.URI).Filename(), c.Options)
+			}
+		case protocol.Rename:
+			if err = flush(); err == nil {
+				err = v.renameFile(span.URI(c.OldURI).Filename(), span.URI(c.NewURI).Filename(), c.Options)
+			}
+		case protocol.Delete:
+			if err = flush(); err == nil {
+				err = v.deleteFile(span.URI(c.URI).Filename(), c.Options)
+			}
+		default:
+			err = fmt.Errorf("unknown resource operation kind %q", c.Kind)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to apply document change %v: %v", i, err)
+		}
+	}
+	return flush()
+}
+
+type vimBufInfo struct {
+	BufNr   int    `json:"bufnr"`
+	Name    string `json:"name"`
+	Changed int    `json:"changed"`
+	Loaded  int    `json:"loaded"`
+	Windows []int  `json:"windows"`
+}
+
+// buffersWithin returns the Vim buffers for path, or for files within path
+// if path is a directory. It is an error for any of those buffers to have
+// unsaved changes, because a resource operation on path would lose them.
+func (v *vimstate) buffersWithin(path string) ([]vimBufInfo, error) {
+	var all []vimBufInfo
+	v.Parse(v.ChannelCall("getbufinfo"), &all)
+	var res []vimBufInfo
+	for _, bi := range all {
+		if bi.Name != path && !strings.HasPrefix(bi.Name, path+string(os.PathSeparator)) {
+			continue
+		}
+		if bi.Changed != 0 {
+			return nil, fmt.Errorf("buffer %v (%v) has unsaved changes", bi.BufNr, bi.Name)
+		}
+		res = append(res, bi)
+	}
+	return res, nil
+}
+
+func (v *vimstate) createFile(fn string, opts resourceOperationOptions) error {
+	if _, err := os.Stat(fn); err == nil {
+		if !opts.Overwrite {
+			if opts.IgnoreIfExists {
+				return nil
+			}
+			return fmt.Errorf("cannot create %v: file already exists", fn)
+		}
+	}
+	bufs, err := v.buffersWithin(fn)
+	if err != nil {
+		return fmt.Errorf("cannot create %v: %v", fn, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(fn), 0777); err != nil {
+		return fmt.Errorf("failed to create parent directory for %v: %v", fn, err)
+	}
+	if err := ioutil.WriteFile(fn, nil, 0666); err != nil {
+		return fmt.Errorf("failed to create %v: %v", fn, err)
+	}
+	// Any (unmodified) buffer for an overwritten file is now stale
+	for _, bi := range bufs {
+		for _, w := range bi.Windows {
+			v.ChannelCall("win_execute", w, "edit")
+		}
+		if len(bi.Windows) == 0 && bi.Loaded != 0 {
+			v.ChannelExf("bunload %v", bi.BufNr)
+		}
+	}
+	return nil
+}
+
+func (v *vimstate) renameFile(from, to string, opts resourceOperationOptions) error {
+	if _, err := os.Stat(to); err == nil && !opts.Overwrite {
+		if opts.IgnoreIfExists {
+			return nil
+		}
+		return fmt.Errorf("cannot rename %v to %v: target already exists", from, to)
+	}
+	bufs, err := v.buffersWithin(from)
+	if err != nil {
+		return fmt.Errorf("cannot rename %v: %v", from, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(to), 0777); err != nil {
+		return fmt.Errorf("failed to create parent directory for %v: %v", to, err)
+	}
+	if err := os.Rename(from, to); err != nil {
+		return fmt.Errorf("failed to rename %v to %v: %v", from, to, err)
+	}
+	// Point any windows showing the old file(s) at the new, then wipe the old
+	// buffers. This results in the usual BufRead and BufDelete autocommands
+	// firing, which in turn keeps gopls up to date.
+	for _, bi := range bufs {
+		nfn := to + strings.TrimPrefix(bi.Name, from)
+		for _, w := range bi.Windows {
+			v.ChannelExf("call win_execute(%v, 'edit '.fnameescape(%q))", w, nfn)
+		}
+		v.ChannelExf("bwipeout %v", bi.BufNr)
+	}
+	return nil
+}
+
+func (v *vimstate) deleteFile(fn string, opts resourceOperationOptions) error {
+	fi, err := os.Stat(fn)
+	if err != nil {
+		if os.IsNotExist(err) && opts.IgnoreIfNotExists {
+			return nil
+		}
+		return fmt.Errorf("cannot delete %v: %v", fn, err)
+	}
+	bufs, err := v.buffersWithin(fn)
+	if err != nil {
+		return fmt.Errorf("cannot delete %v: %v", fn, err)
+	}
+	for _, bi := range bufs {
+		v.ChannelExf("bwipeout %v", bi.BufNr)
+	}
+	if fi.IsDir() && opts.Recursive {
+		err = os.RemoveAll(fn)
+	} else {
+		err = os.Remove(fn)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete %v: %v", fn, err)
+	}
+	return nil
+}
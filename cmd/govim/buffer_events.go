@@ -42,6 +42,11 @@ func (v *vimstate) bufReadPost(args ...json.RawMessage) error {
 			if err := v.redefineHighlights(true); err != nil {
 				v.Logf("failed to update highlights for buffer %d: %v", nb.Num, err)
 			}
+			if fr, ok := v.foldingRanges[cb.Num]; ok && fr.version == cb.Version {
+				if err := v.renderFoldingRanges(cb, fr); err != nil {
+					v.Logf("failed to update fold levels for buffer %d: %v", nb.Num, err)
+				}
+			}
 			return nil
 		}
 		cb.SetContents(nb.Contents())
@@ -135,7 +140,13 @@ func (v *vimstate) bufChanged(args ...json.RawMessage) (interface{}, error) {
 	// add back trailing newline
 	b.SetContents(append(bytes.Join(contents, []byte("\n")), '\n'))
 	v.triggerBufferASTUpdate(b)
-	return nil, v.server.DidChange(context.Background(), params)
+	if err := v.server.DidChange(context.Background(), params); err != nil {
+		return nil, err
+	}
+	v.updateOutline(b)
+	v.updateCodeLenses()
+	v.updateFoldingRanges(b)
+	return nil, v.updateSemanticHighlighting()
 }
 
 func (v *vimstate) bufUnload(args ...json.RawMessage) error {
@@ -158,8 +169,11 @@ func (v *vimstate) handleBufferEvent(b *types.Buffer) error {
 				Text:       string(b.Contents()),
 			},
 		}
-		err := v.server.DidOpen(context.Background(), params)
-		return err
+		if err := v.server.DidOpen(context.Background(), params); err != nil {
+			return err
+		}
+		v.updateFoldingRanges(b)
+		return nil
 	}
 
 	params := &protocol.DidChangeTextDocumentParams{
@@ -173,8 +187,11 @@ func (v *vimstate) handleBufferEvent(b *types.Buffer) error {
 			},
 		},
 	}
-	err := v.server.DidChange(context.Background(), params)
-	return err
+	if err := v.server.DidChange(context.Background(), params); err != nil {
+		return err
+	}
+	v.updateFoldingRanges(b)
+	return nil
 }
 
 func (v *vimstate) deleteCurrentBuffer(args ...json.RawMessage) error {
@@ -195,6 +212,30 @@ func (v *vimstate) deleteCurrentBuffer(args ...json.RawMessage) error {
 		}
 	}
 
+	if cl, ok := v.codeLenses[cb.Num]; ok {
+		if cl.cancel != nil {
+			cl.cancel()
+		}
+		v.clearCodeLenses(cb.Num, cl)
+		delete(v.codeLenses, cb.Num)
+	}
+
+	if fr, ok := v.foldingRanges[cb.Num]; ok {
+		if fr.cancel != nil {
+			fr.cancel()
+		}
+		delete(v.foldingRanges, cb.Num)
+	}
+
+	delete(v.selectionRanges, cb.Num)
+
+	if st, ok := v.semanticTokens[cb.Num]; ok {
+		if st.cancel != nil {
+			st.cancel()
+		}
+		delete(v.semanticTokens, cb.Num)
+	}
+
 	v.ChannelCall("listener_remove", cb.Listener)
 	delete(v.buffers, cb.Num)
 	params := &protocol.DidCloseTextDocumentParams{
@@ -250,7 +291,7 @@ func (g *govimplugin) startProcessBufferUpdates() {
 			// flooded/overloaded first
 			g.tomb.Go(func() error {
 				fset := token.NewFileSet()
-				f, err := parser.ParseFile(fset, upd.name, upd.contents, parser.AllErrors)
+				f, err := parser.ParseFile(fset, upd.name, upd.contents, parser.AllErrors|parser.ParseComments)
 				if err != nil {
 					// This is best efforts so we just log the error as an info
 					// message
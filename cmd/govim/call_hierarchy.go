@@ -0,0 +1,310 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/govim/govim"
+	"github.com/govim/govim/cmd/govim/config"
+	"github.com/govim/govim/cmd/govim/internal/golang_org_x_tools/jsonrpc2"
+	"github.com/govim/govim/cmd/govim/internal/golang_org_x_tools/lsp/protocol"
+	"github.com/govim/govim/cmd/govim/internal/golang_org_x_tools/span"
+)
+
+// errCallHierarchyUnsupported is the error returned by the call hierarchy
+// commands when gopls does not support call hierarchies
+var errCallHierarchyUnsupported = errors.New("call hierarchy is not supported by this version of gopls")
+
+// callTree is the state of a call hierarchy window
+type callTree struct {
+	bufnr int
+
+	// incoming is true if the tree shows callers, false if it shows callees
+	incoming bool
+
+	roots []*callNode
+
+	// rows are the currently visible nodes, in the order in which they
+	// appear in the buffer, i.e. rows[i] is line i+1
+	rows []*callNode
+
+	// lines are the current contents of the buffer
+	lines []string
+}
+
+// callNode is a single function in a call hierarchy
+type callNode struct {
+	item   protocol.CallHierarchyItem
+	parent *callNode
+	depth  int
+
+	// siteURI is the file that contains the call sites fromRanges. For the
+	// callers of a function this is the file of the caller; for the callees
+	// it is the file of the parent. siteURI is empty for a root node
+	siteURI    protocol.DocumentURI
+	fromRanges []protocol.Range
+
+	// fetched indicates whether children has been populated; children are
+	// fetched lazily, the first time a node is expanded
+	fetched  bool
+	children []*callNode
+	expanded bool
+
+	// cycle is set if item also appears as an ancestor of this node. Such
+	// nodes cannot be expanded
+	cycle bool
+}
+
+func (v *vimstate) callersOf(flags govim.CommandFlags, args ...string) error {
+	return v.callHierarchy(flags, true)
+}
+
+func (v *vimstate) calleesOf(flags govim.CommandFlags, args ...string) error {
+	return v.callHierarchy(flags, false)
+}
+
+func (v *vimstate) callHierarchy(flags govim.CommandFlags, incoming bool) error {
+	b, pos, err := v.cursorPos()
+	if err != nil {
+		return fmt.Errorf("failed to determine cursor position: %v", err)
+	}
+	params := &protocol.CallHierarchyPrepareParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: b.ToTextDocumentIdentifier(),
+			Position:     pos.ToPosition(),
+		},
+	}
+	items, err := v.server.PrepareCallHierarchy(context.Background(), params)
+	if jerr, ok := err.(*jsonrpc2.Error); ok && jerr.Code == jsonrpc2.CodeMethodNotFound {
+		return errCallHierarchyUnsupported
+	}
+	if err != nil {
+		return fmt.Errorf("call to gopls.PrepareCallHierarchy failed: %v", err)
+	}
+	if len(items) == 0 {
+		return fmt.Errorf("no function found at cursor")
+	}
+
+	ct := &callTree{
+		incoming: incoming,
+	}
+	for _, item := range items {
+		n := &callNode{
+			item: item,
+		}
+		if err := v.expandCallNode(ct, n); err != nil {
+			return err
+		}
+		ct.roots = append(ct.roots, n)
+	}
+
+	// Only one call hierarchy window at a time. Only replace the existing
+	// window once we know we have a new tree to show
+	if prev := v.callTree; prev != nil {
+		v.callTree = nil
+		if v.ParseInt(v.ChannelCall("bufexists", prev.bufnr)) != 0 {
+			v.ChannelExf("bwipeout %v", prev.bufnr)
+		}
+	}
+
+	name := "govim-callees"
+	if incoming {
+		name = "govim-callers"
+	}
+	mapping := func(key string, fn config.Function) string {
+		return fmt.Sprintf("nnoremap <buffer> <silent> %v :call %v%v(line('.'))<CR>", key, PluginPrefix, fn)
+	}
+	ct.bufnr = v.openScratchWindow(flags.Mods.String(), name, "govimcalls",
+		mapping("<CR>", config.FunctionCallHierarchyJump),
+		mapping("o", config.FunctionCallHierarchyToggle),
+	)
+	v.callTree = ct
+	v.renderCallTree(ct)
+	return nil
+}
+
+// expandCallNode expands n, fetching its children from gopls if that has not
+// already been done
+func (v *vimstate) expandCallNode(ct *callTree, n *callNode) error {
+	if n.cycle {
+		return nil
+	}
+	if !n.fetched {
+		var children []*callNode
+		if ct.incoming {
+			params := &protocol.CallHierarchyIncomingCallsParams{
+				Item: n.item,
+			}
+			calls, err := v.server.IncomingCalls(context.Background(), params)
+			if jerr, ok := err.(*jsonrpc2.Error); ok && jerr.Code == jsonrpc2.CodeMethodNotFound {
+				return errCallHierarchyUnsupported
+			}
+			if err != nil {
+				return fmt.Errorf("call to gopls.IncomingCalls failed: %v", err)
+			}
+			for _, c := range calls {
+				children = append(children, &callNode{
+					item:       c.From,
+					siteURI:    c.From.URI,
+					fromRanges: c.FromRanges,
+				})
+			}
+		} else {
+			params := &protocol.CallHierarchyOutgoingCallsParams{
+				Item: n.item,
+			}
+			calls, err := v.server.OutgoingCalls(context.Background(), params)
+			if jerr, ok := err.(*jsonrpc2.Error); ok && jerr.Code == jsonrpc2.CodeMethodNotFound {
+				return errCallHierarchyUnsupported
+			}
+			if err != nil {
+				return fmt.Errorf("call to gopls.OutgoingCalls failed: %v", err)
+			}
+			for _, c := range calls {
+				children = append(children, &callNode{
+					item:       c.To,
+					siteURI:    n.item.URI,
+					fromRanges: c.FromRanges,
+				})
+			}
+		}
+		for _, c := range children {
+			c.parent = n
+			c.depth = n.depth + 1
+			for a := n; a != nil; a = a.parent {
+				if sameCallItem(a.item, c.item) {
+					c.cycle = true
+					break
+				}
+			}
+		}
+		n.children = children
+		n.fetched = true
+	}
+	n.expanded = true
+	return nil
+}
+
+func sameCallItem(a, b protocol.CallHierarchyItem) bool {
+	return a.URI == b.URI && a.SelectionRange == b.SelectionRange
+}
+
+// renderCallTree updates the call hierarchy buffer to reflect the current
+// expanded state of the tree
+func (v *vimstate) renderCallTree(ct *callTree) {
+	var rows []*callNode
+	var walk func(nodes []*callNode)
+	walk = func(nodes []*callNode) {
+		for _, n := range nodes {
+			rows = append(rows, n)
+			if n.expanded {
+				walk(n.children)
+			}
+		}
+	}
+	walk(ct.roots)
+
+	lines := make([]string, len(rows))
+	for i, n := range rows {
+		lines[i] = v.callNodeLine(n)
+	}
+	v.setScratchLines(ct.bufnr, ct.lines, lines)
+	ct.rows = rows
+	ct.lines = lines
+}
+
+func (v *vimstate) callNodeLine(n *callNode) string {
+	var marker string
+	switch {
+	case n.cycle:
+		marker = "@"
+	case n.fetched && len(n.children) == 0:
+		marker = " "
+	case n.expanded:
+		marker = "-"
+	default:
+		marker = "+"
+	}
+	// For a root node show the location of the function itself, otherwise
+	// show the location of the (first) call site
+	uri, rng := n.item.URI, n.item.SelectionRange
+	if n.siteURI != "" && len(n.fromRanges) > 0 {
+		uri, rng = n.siteURI, n.fromRanges[0]
+	}
+	fn := span.URI(uri).Filename()
+	if rel, err := filepath.Rel(v.workingDirectory, fn); err == nil {
+		fn = rel
+	}
+	line := fmt.Sprintf("%v%v %v %v:%v", strings.Repeat("  ", n.depth), marker, n.item.Name, fn, int(rng.Start.Line)+1)
+	if len(n.fromRanges) > 1 {
+		line += fmt.Sprintf(" (%v calls)", len(n.fromRanges))
+	}
+	return line
+}
+
+// callTreeRow returns the call hierarchy node on the given line of the call
+// hierarchy window
+func (v *vimstate) callTreeRow(args []json.RawMessage) (*callTree, *callNode, error) {
+	ct := v.callTree
+	if ct == nil || v.ParseInt(v.ChannelCall("bufexists", ct.bufnr)) == 0 {
+		v.callTree = nil
+		return nil, nil, fmt.Errorf("no call hierarchy window is open")
+	}
+	line := v.ParseInt(args[0])
+	if line < 1 || line > len(ct.rows) {
+		return ct, nil, nil
+	}
+	return ct, ct.rows[line-1], nil
+}
+
+// callHierarchyToggle expands or collapses the node on the given line of the
+// call hierarchy window
+func (v *vimstate) callHierarchyToggle(args ...json.RawMessage) (interface{}, error) {
+	ct, n, err := v.callTreeRow(args)
+	if err != nil || n == nil {
+		return nil, err
+	}
+	if n.expanded {
+		n.expanded = false
+	} else if err := v.expandCallNode(ct, n); err != nil {
+		return nil, err
+	}
+	v.renderCallTree(ct)
+	return nil, nil
+}
+
+// callHierarchyJump jumps to the call site for the node on the given line of
+// the call hierarchy window, or to the function itself for a root node.
+func (v *vimstate) callHierarchyJump(args ...json.RawMessage) (interface{}, error) {
+	_, n, err := v.callTreeRow(args)
+	if err != nil || n == nil {
+		return nil, err
+	}
+	loc := protocol.Location{
+		URI:   n.item.URI,
+		Range: n.item.SelectionRange,
+	}
+	if n.siteURI != "" && len(n.fromRanges) > 0 {
+		loc = protocol.Location{
+			URI:   n.siteURI,
+			Range: n.fromRanges[0],
+		}
+	}
+	// Jump from the window we were in before entering the call hierarchy
+	// window, so that the call hierarchy window itself is left intact
+	v.ChannelEx("wincmd p")
+	if cb, pos, err := v.cursorPos(); err == nil {
+		v.pushJumpStack(protocol.Location{
+			URI: protocol.DocumentURI(cb.URI()),
+			Range: protocol.Range{
+				Start: pos.ToPosition(),
+				End:   pos.ToPosition(),
+			},
+		})
+	}
+	return nil, v.loadLocation(nil, loc)
+}
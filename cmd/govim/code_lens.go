@@ -0,0 +1,251 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/govim/govim"
+	"github.com/govim/govim/cmd/govim/config"
+	"github.com/govim/govim/cmd/govim/internal/golang_org_x_tools/lsp/protocol"
+	"github.com/govim/govim/cmd/govim/internal/types"
+)
+
+// bufCodeLenses are the code lenses for a buffer
+type bufCodeLenses struct {
+	// version is the buffer version for which lenses were fetched
+	version int
+
+	// lenses are keyed by (1-indexed) line number
+	lenses map[int][]protocol.CodeLens
+
+	// popups are the IDs of the popups used to render lenses
+	popups []int
+
+	// cancel cancels the in-flight codeLens request for the buffer, if any.
+	// It must only be used on the Vim "thread"
+	cancel context.CancelFunc
+}
+
+func (v *vimstate) codeLensEnabled() bool {
+	return v.config.CodeLens != nil && *v.config.CodeLens
+}
+
+// updateCodeLenses (re)fetches code lenses for all visible buffers whose
+// lenses are out of date. It is called when the user stops being busy (i.e.
+// after &updatetime) and when a buffer changes, but never whilst the user is
+// busy.
+func (v *vimstate) updateCodeLenses() {
+	if !v.codeLensEnabled() || v.userBusy {
+		return
+	}
+	seen := make(map[int]bool)
+	for _, w := range v.Viewport().Windows {
+		b, ok := v.buffers[w.BufNr]
+		if !ok || seen[b.Num] {
+			continue
+		}
+		seen[b.Num] = true
+		cl := v.codeLenses[b.Num]
+		if cl == nil {
+			cl = &bufCodeLenses{version: -1}
+			v.codeLenses[b.Num] = cl
+		}
+		if cl.version == b.Version {
+			continue
+		}
+		v.fetchCodeLenses(b, cl)
+	}
+}
+
+func (v *vimstate) fetchCodeLenses(b *types.Buffer, cl *bufCodeLenses) {
+	if cl.cancel != nil {
+		cl.cancel()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cl.cancel = cancel
+	version := b.Version
+	params := &protocol.CodeLensParams{
+		TextDocument: b.ToTextDocumentIdentifier(),
+	}
+	server := v.server
+	v.tomb.Go(func() error {
+		res, err := server.CodeLens(ctx, params)
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+		if err != nil {
+			v.Logf("codeLens call failed: %v", err)
+			return nil
+		}
+		v.Schedule(func(govim.Govim) error {
+			select {
+			case <-ctx.Done():
+				return nil
+			default:
+			}
+			cl.cancel = nil
+			// The buffer might have changed (or gone away) in the meantime, in
+			// which case another update will follow
+			if nb, ok := v.buffers[b.Num]; !ok || nb != b || b.Version != version || v.codeLenses[b.Num] != cl {
+				return nil
+			}
+			cl.version = version
+			cl.lenses = make(map[int][]protocol.CodeLens)
+			for _, l := range res {
+				line := int(l.Range.Start.Line) + 1
+				cl.lenses[line] = append(cl.lenses[line], l)
+			}
+			return v.renderCodeLenses(b, cl)
+		})
+		return nil
+	})
+}
+
+// renderCodeLenses shows the code lenses in cl by means of a zero-length text
+// property at the end of each line with lenses, and a popup attached to that
+// text property showing the lens titles. Vim does not (yet) support virtual
+// text; attaching the popups to text properties means they move with the
+// text.
+func (v *vimstate) renderCodeLenses(b *types.Buffer, cl *bufCodeLenses) error {
+	v.clearCodeLenses(b.Num, cl)
+	if len(cl.lenses) == 0 {
+		return nil
+	}
+	var wins []int
+	v.Parse(v.ChannelCall("win_findbuf", b.Num), &wins)
+	if len(wins) == 0 {
+		return nil
+	}
+	var lines []int
+	for l := range cl.lenses {
+		lines = append(lines, l)
+	}
+	sort.Ints(lines)
+
+	v.BatchStart()
+	defer v.BatchCancelIfNotEnded()
+	contents := strings.Split(string(b.Contents()), "\n")
+	for _, l := range lines {
+		if l > len(contents) {
+			continue
+		}
+		col := len(contents[l-1]) + 1
+		id := types.CodeLensTextPropID + l
+		v.BatchAssertChannelCall(assertPropAdd, "prop_add", l, col,
+			propAddDict{string(config.HighlightCodeLens), id, l, col, b.Num},
+		)
+		var titles []string
+		for _, lens := range cl.lenses[l] {
+			titles = append(titles, lens.Command.Title)
+		}
+		v.BatchChannelCall("popup_create", strings.Join(titles, " | "), map[string]interface{}{
+			"textprop":    string(config.HighlightCodeLens),
+			"textpropid":  id,
+			"textpropwin": wins[0],
+			"pos":         "topleft",
+			"line":        -1,
+			"col":         2,
+			"highlight":   string(config.HighlightCodeLens),
+			"wrap":        false,
+			"zindex":      1,
+		})
+	}
+	res := v.MustBatchEnd()
+	// Every other result is a popup ID
+	for i := 1; i < len(res); i += 2 {
+		cl.popups = append(cl.popups, v.ParseInt(res[i]))
+	}
+	return nil
+}
+
+// clearCodeLenses removes the rendered code lenses for buffer bufnr
+func (v *vimstate) clearCodeLenses(bufnr int, cl *bufCodeLenses) {
+	v.BatchStart()
+	defer v.BatchCancelIfNotEnded()
+	for _, id := range cl.popups {
+		v.BatchChannelCall("popup_close", id)
+	}
+	if b, ok := v.buffers[bufnr]; ok && b.Loaded {
+		v.BatchChannelCall("prop_remove", struct {
+			Type  string `json:"type"`
+			BufNr int    `json:"bufnr"`
+			All   int    `json:"all"`
+		}{string(config.HighlightCodeLens), bufnr, 1})
+	}
+	v.MustBatchEnd()
+	cl.popups = nil
+}
+
+// removeAllCodeLenses removes all rendered code lenses, and forgets all
+// cached code lenses
+func (v *vimstate) removeAllCodeLenses() {
+	for bufnr, cl := range v.codeLenses {
+		if cl.cancel != nil {
+			cl.cancel()
+		}
+		v.clearCodeLenses(bufnr, cl)
+	}
+	v.codeLenses = make(map[int]*bufCodeLenses)
+}
+
+// codeLens runs the code lens on the cursor line. If there is more than one
+// lens on the line, the title of the lens to run must be given as an
+// argument.
+func (v *vimstate) codeLens(flags govim.CommandFlags, args ...string) error {
+	b, pos, err := v.cursorPos()
+	if err != nil {
+		return fmt.Errorf("failed to determine cursor position: %v", err)
+	}
+	cl, ok := v.codeLenses[b.Num]
+	if !ok || cl.version != b.Version {
+		return fmt.Errorf("code lenses for %v are not up to date", b.Name)
+	}
+	lenses := cl.lenses[pos.Line()]
+	var lens *protocol.CodeLens
+	switch {
+	case len(lenses) == 0:
+		return fmt.Errorf("no code lens on line %v", pos.Line())
+	case len(args) == 1:
+		for i := range lenses {
+			if lenses[i].Command.Title == args[0] {
+				lens = &lenses[i]
+				break
+			}
+		}
+		if lens == nil {
+			return fmt.Errorf("no code lens %q on line %v", args[0], pos.Line())
+		}
+	case len(lenses) == 1:
+		lens = &lenses[0]
+	default:
+		var titles []string
+		for _, l := range lenses {
+			titles = append(titles, fmt.Sprintf("%q", l.Command.Title))
+		}
+		return fmt.Errorf("multiple code lenses on line %v (%v); specify one as an argument", pos.Line(), strings.Join(titles, ", "))
+	}
+
+	params := &protocol.ExecuteCommandParams{
+		Command:   lens.Command.Command,
+		Arguments: lens.Command.Arguments,
+	}
+	// Running a command might result in gopls sending a workspace/applyEdit
+	// request, which needs the Vim "thread" to be free; hence the command
+	// must be executed asynchronously
+	server := v.server
+	v.tomb.Go(func() error {
+		if _, err := server.ExecuteCommand(context.Background(), params); err != nil {
+			v.Logf("failed to run code lens %q: %v", lens.Command.Title, err)
+			v.Schedule(func(govim.Govim) error {
+				v.ChannelExf("echohl ErrorMsg | echom %q | echohl None", fmt.Sprintf("code lens %q failed: %v", lens.Command.Title, err))
+				return nil
+			})
+		}
+		return nil
+	})
+	return nil
+}
@@ -4,8 +4,11 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strconv"
+	"strings"
 
 	"github.com/govim/govim"
+	"github.com/govim/govim/cmd/govim/internal/golang_org_x_tools/jsonrpc2"
 	"github.com/govim/govim/cmd/govim/internal/golang_org_x_tools/lsp/protocol"
 	"github.com/govim/govim/cmd/govim/internal/types"
 )
@@ -57,19 +60,151 @@ func (v *vimstate) complete(args ...json.RawMessage) (interface{}, error) {
 		v.lastCompleteResults = res
 		return start, nil
 	} else {
-		var matches []govim.CompleteItem
-		for _, i := range v.lastCompleteResults.Items {
-			matches = append(matches, govim.CompleteItem{
-				Abbr:     i.Label,
-				Menu:     i.Detail,
-				Word:     i.TextEdit.NewText,
-				Info:     i.Documentation,
-				UserData: "govim",
-			})
-		}
+		return completeItems(v.lastCompleteResults.Items), nil
+	}
+}
+
+// completeUserDataPrefix prefixes the user_data of each complete item that
+// govim gives to Vim. It is followed by the index of the item in
+// lastCompleteResults, such that the chosen item can be found even where
+// several items have the same text
+const completeUserDataPrefix = "govim:"
+
+// completeItems converts LSP completion items to Vim complete items
+func completeItems(items []protocol.CompletionItem) []govim.CompleteItem {
+	var matches []govim.CompleteItem
+	for n, i := range items {
+		matches = append(matches, completeItem(n, i))
+	}
+	return matches
+}
+
+// completeItem converts the LSP completion item i, at index n in the
+// completion results, to a Vim complete item
+func completeItem(n int, i protocol.CompletionItem) govim.CompleteItem {
+	word := i.TextEdit.NewText
+	if i.InsertTextFormat == protocol.SnippetTextFormat {
+		// Vim inserts the plain text; the tab stops are marked once
+		// the item is chosen (see completeDone)
+		word, _ = parseSnippet(word)
+	}
+	return govim.CompleteItem{
+		Abbr:     i.Label,
+		Menu:     i.Detail,
+		Word:     word,
+		Info:     i.Documentation,
+		Kind:     completionItemKind(i.Kind),
+		UserData: completeUserDataPrefix + strconv.Itoa(n),
+		// gopls can return distinct items with the same text, e.g.
+		// the unimported packages math/rand and crypto/rand
+		Dup: 1,
+	}
+}
 
-		return matches, nil
+// completionItemKind maps an LSP completion item kind to the single letter
+// kinds that Vim uses by convention (see :help complete-items)
+func completionItemKind(k protocol.CompletionItemKind) string {
+	switch k {
+	case protocol.VariableCompletion:
+		return "v"
+	case protocol.FunctionCompletion, protocol.MethodCompletion, protocol.ConstructorCompletion:
+		return "f"
+	case protocol.FieldCompletion, protocol.PropertyCompletion, protocol.EnumMemberCompletion:
+		return "m"
+	case protocol.ClassCompletion, protocol.InterfaceCompletion, protocol.StructCompletion,
+		protocol.EnumCompletion, protocol.TypeParameterCompletion:
+		return "t"
+	case protocol.ConstantCompletion:
+		return "d"
 	}
+	return ""
+}
+
+// lastCompleteItem returns the item in the last completion results that
+// corresponds to the Vim complete item ci, or nil if there is no such item
+func (v *vimstate) lastCompleteItem(ci govim.CompleteItem) *protocol.CompletionItem {
+	if !strings.HasPrefix(ci.UserData, completeUserDataPrefix) || v.lastCompleteResults == nil {
+		return nil
+	}
+	n, err := strconv.Atoi(strings.TrimPrefix(ci.UserData, completeUserDataPrefix))
+	if err != nil || n < 0 || n >= len(v.lastCompleteResults.Items) {
+		return nil
+	}
+	return &v.lastCompleteResults.Items[n]
+}
+
+// completeChanged is called as the selected completion item changes. If the
+// selected item has no documentation, it is resolved asynchronously and, if
+// that yields documentation, shown in the completion info popup. For this to
+// work, 'completeopt' must include "popup" or "popuphidden".
+func (v *vimstate) completeChanged(args ...json.RawMessage) error {
+	var ev struct {
+		CompletedItem govim.CompleteItem `json:"completed_item"`
+	}
+	v.Parse(args[0], &ev)
+	if v.cancelCompletionResolve != nil {
+		v.cancelCompletionResolve()
+		v.cancelCompletionResolve = nil
+	}
+	if v.completionResolveUnsupported {
+		return nil
+	}
+	item := v.lastCompleteItem(ev.CompletedItem)
+	if item == nil || item.Documentation != "" {
+		return nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	v.cancelCompletionResolve = cancel
+	results := v.lastCompleteResults
+	params := *item
+	server := v.server
+	v.tomb.Go(func() error {
+		res, err := server.Resolve(ctx, &params)
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+		if jerr, ok := err.(*jsonrpc2.Error); ok && jerr.Code == jsonrpc2.CodeMethodNotFound {
+			v.Schedule(func(govim.Govim) error {
+				if !v.completionResolveUnsupported {
+					v.Logf("gopls does not support completionItem/resolve; no longer resolving documentation")
+					v.completionResolveUnsupported = true
+				}
+				return nil
+			})
+			return nil
+		}
+		if err != nil {
+			v.Logf("completionItem/resolve failed: %v", err)
+			return nil
+		}
+		if res == nil || res.Documentation == "" {
+			return nil
+		}
+		v.Schedule(func(govim.Govim) error {
+			select {
+			case <-ctx.Done():
+				return nil
+			default:
+			}
+			v.cancelCompletionResolve = nil
+			if v.lastCompleteResults != results {
+				return nil
+			}
+			// Remember the documentation should the item be selected again
+			item.Documentation = res.Documentation
+			id := v.ParseInt(v.ChannelCall("popup_findinfo"))
+			if id == 0 {
+				return nil
+			}
+			v.ChannelCall("popup_settext", id, strings.Split(res.Documentation, "\n"))
+			v.ChannelCall("popup_show", id)
+			return nil
+		})
+		return nil
+	})
+	return nil
 }
 
 func (v *vimstate) completeDone(args ...json.RawMessage) error {
@@ -78,26 +213,49 @@ func (v *vimstate) completeDone(args ...json.RawMessage) error {
 	if !ok {
 		return fmt.Errorf("failed to resolve buffer %v", currBufNr)
 	}
+	if v.cancelCompletionResolve != nil {
+		v.cancelCompletionResolve()
+		v.cancelCompletionResolve = nil
+	}
 	var chosen govim.CompleteItem
 	v.Parse(args[1], &chosen)
 	if chosen.Word == "" {
 		return nil
 	}
-	if chosen.UserData != "govim" {
+	// An item has been inserted, so any asynchronous completion is over
+	v.cancelAsyncCompletion()
+	if !strings.HasPrefix(chosen.UserData, completeUserDataPrefix) {
 		return nil
 	}
-	var match *protocol.CompletionItem
-	for _, c := range v.lastCompleteResults.Items {
-		if c.Label == chosen.Abbr {
-			match = &c
-			break
-		}
-	}
+	match := v.lastCompleteItem(chosen)
 	if match == nil {
 		return fmt.Errorf("failed to find match for completed item %#v", chosen)
 	}
-	if len(match.AdditionalTextEdits) == 0 {
-		return nil
+	var stops []snippetStop
+	if match.InsertTextFormat == protocol.SnippetTextFormat {
+		var text string
+		text, stops = parseSnippet(match.TextEdit.NewText)
+		if strings.Contains(text, "\n") {
+			stops = nil
+		}
+	}
+	if len(stops) > 0 {
+		// The cursor is just after the inserted text. Mark the tab stops
+		// before applying any additional edits, so that the marks move with
+		// the text
+		_, pos, err := v.cursorPos()
+		if err != nil {
+			return fmt.Errorf("failed to determine cursor position: %v", err)
+		}
+		v.startSnippet(b, pos.Line(), pos.Col()-len(chosen.Word), stops)
+	}
+	if len(match.AdditionalTextEdits) > 0 {
+		if err := v.applyProtocolTextEdits(b, match.AdditionalTextEdits); err != nil {
+			return err
+		}
+	}
+	if len(stops) > 0 {
+		return v.gotoSnippetStop()
 	}
-	return v.applyProtocolTextEdits(b, match.AdditionalTextEdits)
+	return nil
 }
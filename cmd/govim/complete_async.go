@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/govim/govim"
+	"github.com/govim/govim/cmd/govim/internal/golang_org_x_tools/lsp/protocol"
+	"github.com/govim/govim/cmd/govim/internal/types"
+)
+
+// completionTriggerCharacters are the characters that, when typed in insert
+// mode, trigger an asynchronous completion request
+const completionTriggerCharacters = "."
+
+const exprAsyncCompletePos = `{"bufnr": bufnr(""), "line": line("."), "col": col("."), "text": getline(".")}`
+
+type asyncCompletePos struct {
+	BufNr int    `json:"bufnr"`
+	Line  int    `json:"line"`
+	Col   int    `json:"col"`
+	Text  string `json:"text"`
+}
+
+// completion returns the (1-indexed byte) column at which the identifier
+// that ends at the cursor starts, and that identifier so far. The identifier
+// is the empty string if the character before the cursor is not part of an
+// identifier.
+func (p asyncCompletePos) completion() (int, string) {
+	before := p.Text
+	if p.Col-1 < len(before) {
+		before = before[:p.Col-1]
+	}
+	start := len(before)
+	for start > 0 {
+		r, size := utf8.DecodeLastRuneInString(before[:start])
+		if r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			break
+		}
+		start -= size
+	}
+	return start + 1, before[start:]
+}
+
+// asyncCompletion is the state of an asynchronous completion, started by
+// typing one of completionTriggerCharacters in insert mode
+type asyncCompletion struct {
+	// bufnr, line and col identify where the completion starts, i.e. just
+	// after the trigger character. col is a 1-indexed byte column
+	bufnr int
+	line  int
+	col   int
+
+	// token is the work done progress token of the completion request
+	token string
+
+	// cancel cancels the completion request. It is nil once the results
+	// are in
+	cancel context.CancelFunc
+
+	// results are the completion results, or nil if the request is still in
+	// flight
+	results *protocol.CompletionList
+}
+
+// completeTextChangedI is called as text is changed in insert mode. If
+// config.Config.CompletionAsync is enabled and a completion trigger character
+// has just been typed, completion results are requested from gopls in the
+// background. Until the cursor leaves the identifier that follows the
+// trigger character, the results are (re)filtered by the identifier typed so
+// far and shown via complete(). Leaving the identifier cancels any in-flight
+// request.
+func (v *vimstate) completeTextChangedI(args ...json.RawMessage) error {
+	if v.config.CompletionAsync == nil || !*v.config.CompletionAsync {
+		return nil
+	}
+	var pos asyncCompletePos
+	v.Parse(args[0], &pos)
+	b, ok := v.buffers[pos.BufNr]
+	if !ok {
+		return nil
+	}
+	start, prefix := pos.completion()
+	if ac := v.asyncCompletion; ac != nil && ac.bufnr == pos.BufNr && ac.line == pos.Line && ac.col == start {
+		// Still completing the same identifier. If the request is in flight
+		// the results are filtered when they arrive
+		if ac.results != nil {
+			v.showAsyncCompletion(ac, prefix)
+		}
+		return nil
+	}
+	v.cancelAsyncCompletion()
+	if prefix != "" || start == 1 || !strings.ContainsRune(completionTriggerCharacters, rune(pos.Text[start-2])) {
+		return nil
+	}
+	point, err := types.PointFromVim(b, pos.Line, pos.Col)
+	if err != nil {
+		return fmt.Errorf("failed to determine cursor position: %v", err)
+	}
+	server := v.server
+
+	ctx, cancel := context.WithCancel(context.Background())
+	v.asyncCompletionSeq++
+	ac := &asyncCompletion{
+		bufnr:  pos.BufNr,
+		line:   pos.Line,
+		col:    start,
+		token:  fmt.Sprintf("govim-completion-%v", v.asyncCompletionSeq),
+		cancel: cancel,
+	}
+	v.asyncCompletion = ac
+
+	params := &protocol.CompletionParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: b.ToTextDocumentIdentifier(),
+			Position:     point.ToPosition(),
+		},
+		Context: protocol.CompletionContext{
+			TriggerKind:      protocol.TriggerCharacter,
+			TriggerCharacter: pos.Text[start-2 : start-1],
+		},
+	}
+	params.WorkDoneToken = ac.token
+	v.tomb.Go(func() error {
+		res, err := server.Completion(ctx, params)
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+		if err != nil {
+			v.Logf("asynchronous completion failed: %v", err)
+			res = &protocol.CompletionList{}
+		}
+		v.Schedule(func(govim.Govim) error {
+			select {
+			case <-ctx.Done():
+				return nil
+			default:
+			}
+			ac.cancel = nil
+			ac.results = res
+			// The user may well have typed more whilst we were waiting
+			var pos asyncCompletePos
+			v.Parse(v.ChannelExpr(exprAsyncCompletePos), &pos)
+			start, prefix := pos.completion()
+			if pos.BufNr != ac.bufnr || pos.Line != ac.line || start != ac.col {
+				v.asyncCompletion = nil
+				return nil
+			}
+			v.showAsyncCompletion(ac, prefix)
+			return nil
+		})
+		return nil
+	})
+	return nil
+}
+
+// completeInsertLeave cancels any asynchronous completion on leaving insert
+// mode
+func (v *vimstate) completeInsertLeave(args ...json.RawMessage) error {
+	v.cancelAsyncCompletion()
+	return nil
+}
+
+// showAsyncCompletion shows the results of ac that match prefix via
+// complete(), unless the completion popup menu is already visible, in which
+// case Vim takes care of filtering. Matching is by case-insensitive prefix.
+func (v *vimstate) showAsyncCompletion(ac *asyncCompletion, prefix string) {
+	if v.ParseString(v.ChannelExpr("mode()")) != "i" || v.ParseInt(v.ChannelExpr("pumvisible()")) != 0 {
+		return
+	}
+	lprefix := strings.ToLower(prefix)
+	var matches []govim.CompleteItem
+	for n, i := range ac.results.Items {
+		text := i.FilterText
+		if text == "" {
+			text = i.Label
+		}
+		if strings.HasPrefix(strings.ToLower(text), lprefix) {
+			matches = append(matches, completeItem(n, i))
+		}
+	}
+	if len(matches) == 0 || len(matches) == 1 && matches[0].Word == prefix {
+		// Nothing to show, or nothing more to complete
+		return
+	}
+	// completeChanged and completeDone find the chosen item in
+	// lastCompleteResults
+	v.lastCompleteResults = ac.results
+	v.ChannelCall("complete", ac.col, matches)
+}
+
+// cancelAsyncCompletion forgets the current asynchronous completion, if any,
+// cancelling its request if it is still in flight
+func (v *vimstate) cancelAsyncCompletion() {
+	ac := v.asyncCompletion
+	if ac == nil {
+		return
+	}
+	v.asyncCompletion = nil
+	if ac.cancel == nil {
+		return
+	}
+	// Cancelling the context cancels the request itself. If gopls has
+	// started reporting progress against our token, the work associated with
+	// that progress also needs to be cancelled
+	ac.cancel()
+	v.progressLock.Lock()
+	_, ok := v.progress[progressKey(ac.token)]
+	v.progressLock.Unlock()
+	server := v.server
+	if !ok {
+		return
+	}
+	v.tomb.Go(func() error {
+		params := &protocol.WorkDoneProgressCancelParams{
+			Token: ac.token,
+		}
+		if err := server.WorkDoneProgressCancel(context.Background(), params); err != nil {
+			v.Logf("failed to cancel completion progress %v: %v", ac.token, err)
+		}
+		return nil
+	})
+}
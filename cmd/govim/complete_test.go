@@ -0,0 +1,31 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/govim/govim/cmd/govim/internal/golang_org_x_tools/lsp/protocol"
+)
+
+func TestLastCompleteItem(t *testing.T) {
+	// Distinct items with the same label and text, as gopls returns for the
+	// unimported packages math/rand and crypto/rand
+	results := &protocol.CompletionList{
+		Items: []protocol.CompletionItem{
+			{Label: "Int", Detail: "func(max *big.Int) (*big.Int, error)", TextEdit: &protocol.TextEdit{NewText: "Int"}},
+			{Label: "Int", Detail: "func() int", TextEdit: &protocol.TextEdit{NewText: "Int"}},
+		},
+	}
+	v := &vimstate{lastCompleteResults: results}
+	for n, ci := range completeItems(results.Items) {
+		if got := v.lastCompleteItem(ci); got != &results.Items[n] {
+			t.Errorf("item %v: got %+v; want %+v", n, got, results.Items[n])
+		}
+	}
+	ci := completeItem(0, results.Items[0])
+	for _, ud := range []string{"", "govim", "govim:x", "govim:2", "other:0"} {
+		ci.UserData = ud
+		if got := v.lastCompleteItem(ci); got != nil {
+			t.Errorf("user_data %q: got %+v; want nil", ud, got)
+		}
+	}
+}
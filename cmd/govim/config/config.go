@@ -56,6 +56,26 @@ type Config struct {
 	// Default: true
 	QuickfixAutoDiagnostics *bool `json:",omitempty"`
 
+	// DiagnosticsList controls where diagnostics are listed, i.e. which list
+	// QuickfixAutoDiagnostics and CommandQuickfixDiagnostics populate.
+	// Options are given by constants of type DiagnosticsList. With
+	// DiagnosticsListLocationList, each window's location list holds the
+	// diagnostics for its own buffer, leaving the quickfix list free for
+	// references, implementations, build results etc.
+	//
+	// Default: DiagnosticsListQuickfix
+	DiagnosticsList *DiagnosticsList `json:",omitempty"`
+
+	// InlineDiagnostics is a boolean (0 or 1 in VimScript) that controls
+	// whether diagnostic messages are shown, truncated, after the end of the
+	// line to which they apply. Where a line has more than one diagnostic,
+	// the most severe is shown. Messages are hidden on the cursor line in
+	// insert mode. Requires a Vim that supports text properties with text
+	// (Vim 9.0.0121 or later); otherwise this option has no effect.
+	//
+	// Default: false
+	InlineDiagnostics *bool `json:",omitempty"`
+
 	// QuickfixSigns is a boolean (0 or 1 in VimScript) that controls whether
 	// diagnostic errors should be shown with signs in the gutter. When enabled,
 	// govim waits for updatetime (help updatetime) before placing signs
@@ -221,6 +241,190 @@ type Config struct {
 	// completeopt=menu,popup and Vim+govim will behave approximately like
 	// completeopt+=longest.
 	ExperimentalWorkaroundCompleteoptLongest *bool `json:",omitempty"`
+
+	// SignatureHelp enables a popup showing the signature of the function
+	// being called whilst typing call arguments in insert mode. The active
+	// parameter is highlighted via the highlight group
+	// GOVIMSignatureActiveParameter.
+	//
+	// Default: false
+	SignatureHelp *bool `json:",omitempty"`
+
+	// ExperimentalSignatureHelpPopupOptions is a map of options to apply when
+	// creating the signature help popup (see SignatureHelp). It follows the
+	// same rules as ExperimentalCursorTriggeredHoverPopupOptions: the map is
+	// used as is in the call to popup_create, except for the values of line
+	// and col which are interpreted relative to the cursor position.
+	//
+	// This is an experimental feature designed to help iterate on the most
+	// sensible out-of-the-box defaults for signature help popups. It might go
+	// away in the future, be renamed etc.
+	//
+	// Default: nil
+	ExperimentalSignatureHelpPopupOptions *map[string]interface{} `json:",omitempty"`
+
+	// CodeLens enables the display of gopls code lenses (e.g. to run go
+	// generate) at the end of the lines to which they apply in visible
+	// buffers. Lenses are fetched once the user is idle (see :help
+	// updatetime), and are styled via the highlight group GOVIMCodeLens. The
+	// lens on the cursor line can be run with CommandCodeLens.
+	//
+	// Default: false
+	CodeLens *bool `json:",omitempty"`
+
+	// ProgressPopup enables a popup in the top right corner of the screen
+	// that shows the progress of long running gopls operations, e.g. loading
+	// packages or running go generate. Regardless of this setting, the
+	// current progress is available via FunctionProgress, e.g. for use in a
+	// statusline.
+	//
+	// Default: false
+	ProgressPopup *bool `json:",omitempty"`
+
+	// ShowMessageRequestTimeout is the string-format time.Duration for which
+	// a popup menu of actions requested by gopls (via
+	// window/showMessageRequest) waits for the user to pick an action. Once
+	// the timeout has elapsed the popup is closed and gopls is told no action
+	// was picked. Zero seconds means wait indefinitely.
+	//
+	// Default: "30s"
+	ShowMessageRequestTimeout *string `json:",omitempty"`
+
+	// GoplsCrashPolicy configures what happens when gopls exits unexpectedly.
+	// Options are given by constants of type GoplsCrashPolicy. gopls can
+	// also be restarted at any time via CommandGoplsRestart.
+	//
+	// Default: GoplsCrashPolicyRestart
+	GoplsCrashPolicy *GoplsCrashPolicy `json:",omitempty"`
+
+	// CompletionSnippets enables snippet completion items, e.g. function
+	// calls with a placeholder for each parameter. The placeholders can be
+	// visited via FunctionSnippetJump. Because snippet support is negotiated
+	// when gopls starts, changing this setting restarts gopls.
+	//
+	// Default: false
+	CompletionSnippets *bool `json:",omitempty"`
+
+	// CompletionAsync enables asynchronous completion whilst typing in insert
+	// mode. Completion is triggered by typing "." and the results are shown
+	// via complete() once gopls responds, without blocking Vim in the
+	// meantime. The results are then filtered by govim as the typed prefix
+	// grows. A request that is still in flight when the cursor leaves the
+	// completion context (for example by typing a non-identifier character or
+	// leaving insert mode) is cancelled. Omni completion is unaffected. To
+	// avoid the first match being inserted as you type, 'completeopt' should
+	// include "noinsert" or "noselect".
+	//
+	// Default: false
+	CompletionAsync *bool `json:",omitempty"`
+
+	// GoToMultipleLocations configures how CommandGoToDef, CommandGoToTypeDef
+	// and CommandGoToDecl present multiple target locations. Options are
+	// given by constants of type GoToMultipleLocations.
+	//
+	// Default: GoToMultipleLocationsPopup
+	GoToMultipleLocations *GoToMultipleLocations `json:",omitempty"`
+
+	// RenamePreview configures CommandRename to show a preview of the
+	// changes to each affected file before anything is changed. Changes are
+	// accepted or rejected per file from within the preview. Command
+	// modifiers given to CommandRename (e.g. :vertical) control where the
+	// preview window is opened; the default is :botright
+	//
+	// Default: false
+	RenamePreview *bool `json:",omitempty"`
+
+	// MultiFileEditStrategy configures how files that are not already open in
+	// a window are opened when an edit that spans multiple files (for
+	// example a rename) is applied. Options are given by constants of type
+	// MultiFileEditStrategy.
+	//
+	// Default: MultiFileEditStrategySplit
+	MultiFileEditStrategy *MultiFileEditStrategy `json:",omitempty"`
+
+	// FoldingRanges enables code folding driven by gopls folding ranges. The
+	// ranges are fetched as buffers are opened and changed, and are used by
+	// the GOVIMFoldExpr() function, e.g.:
+	//
+	//     setlocal foldmethod=expr foldexpr=GOVIMFoldExpr()
+	//
+	// Default: false
+	FoldingRanges *bool `json:",omitempty"`
+
+	// FoldingRangeKinds is the list of the kinds of folding range that fold
+	// (see FoldingRanges). Options are given by constants of type
+	// FoldingRangeKind.
+	//
+	// Default: all kinds
+	FoldingRangeKinds *[]FoldingRangeKind `json:",omitempty"`
+
+	// SemanticHighlighting enables highlighting based on the semantic tokens
+	// reported by gopls, layered on top of the regular syntax highlighting.
+	// Package names, types, parameters, variables, fields, functions and
+	// methods are highlighted via the GOVIMSem* highlight groups, e.g.
+	// GOVIMSemParameter, which can be overridden as required. Only the lines
+	// visible in a window are highlighted. Requires a version of gopls that
+	// supports semantic tokens.
+	//
+	// Default: false
+	SemanticHighlighting *bool `json:",omitempty"`
+
+	// DiagnosticsQuickfixSeverity is the minimum severity of the diagnostics
+	// that populate the quickfix window. Options are given by constants of
+	// type Severity.
+	//
+	// Default: SeverityHint
+	DiagnosticsQuickfixSeverity *Severity `json:",omitempty"`
+
+	// DiagnosticsSignsSeverity is the minimum severity of the diagnostics
+	// that are shown as signs (see QuickfixSigns). Options are given by
+	// constants of type Severity.
+	//
+	// Default: SeverityHint
+	DiagnosticsSignsSeverity *Severity `json:",omitempty"`
+
+	// DiagnosticsHighlightSeverity is the minimum severity of the
+	// diagnostics that are highlighted (see HighlightDiagnostics). Options
+	// are given by constants of type Severity.
+	//
+	// Default: SeverityHint
+	DiagnosticsHighlightSeverity *Severity `json:",omitempty"`
+
+	// DiagnosticsHoverSeverity is the minimum severity of the diagnostics
+	// that are shown in the hover popup (see HoverDiagnostics). Options are
+	// given by constants of type Severity.
+	//
+	// Default: SeverityHint
+	DiagnosticsHoverSeverity *Severity `json:",omitempty"`
+
+	// DiagnosticsInlineSeverity is the minimum severity of the diagnostics
+	// that are shown inline (see InlineDiagnostics). Options are given by
+	// constants of type Severity.
+	//
+	// Default: SeverityHint
+	DiagnosticsInlineSeverity *Severity `json:",omitempty"`
+
+	// DiagnosticsIncludeSources is the list of diagnostic sources, e.g.
+	// "compiler", "printf" or the name of a staticcheck analyzer such as
+	// "SA4006", that are shown. An empty list includes all sources.
+	//
+	// Default: []
+	DiagnosticsIncludeSources *[]string `json:",omitempty"`
+
+	// DiagnosticsExcludeSources is the list of diagnostic sources that are
+	// not shown, applied after DiagnosticsIncludeSources.
+	//
+	// Default: []
+	DiagnosticsExcludeSources *[]string `json:",omitempty"`
+
+	// DiagnosticsExcludePaths is a list of glob patterns for files whose
+	// diagnostics are not shown. Patterns that contain a slash match paths
+	// relative to the working directory, where ** matches any number of
+	// directories, e.g. "vendor/**". Other patterns match the file name,
+	// e.g. "*_gen.go".
+	//
+	// Default: []
+	DiagnosticsExcludePaths *[]string `json:",omitempty"`
 }
 
 type Command string
@@ -231,6 +435,18 @@ const (
 	// &switchbuf
 	CommandGoToDef Command = "GoToDef"
 
+	// CommandGoToTypeDef jumps to the definition of the type of the identifier
+	// under the cursor, pushing the current location onto the jump stack.
+	// CommandGoToTypeDef respects &switchbuf
+	CommandGoToTypeDef Command = "GoToTypeDef"
+
+	// CommandGoToDecl jumps to the declaration of the identifier under the
+	// cursor, pushing the current location onto the jump stack. In Go the
+	// declaration of an identifier is its definition, so where gopls does not
+	// support declarations CommandGoToDecl is equivalent to CommandGoToDef.
+	// CommandGoToDecl respects &switchbuf
+	CommandGoToDecl Command = "GoToDecl"
+
 	// CommandGoToPrevDef jumps to the previous location in the jump stack.
 	// CommandGoToPrevDef respects &switchbuf
 	CommandGoToPrevDef Command = "GoToPrevDef"
@@ -255,9 +471,21 @@ const (
 
 	// CommandRename renames the identifier under the cursor. If provided with an
 	// argument, that argument is used as the new name. If not, the user is
-	// prompted for the new identifier name.
+	// prompted for the new identifier name. See also Config.RenamePreview and
+	// Config.MultiFileEditStrategy.
 	CommandRename Command = "Rename"
 
+	// CommandUndoEdit undoes the most recent multi-file edit applied by
+	// govim, for example via CommandRename or CommandSuggestedFixes, as a
+	// single operation across all the buffers it changed. It fails without
+	// changing anything if any of those buffers has changed since.
+	CommandUndoEdit Command = "UndoEdit"
+
+	// CommandRedoEdit reapplies the edit most recently undone via
+	// CommandUndoEdit. It fails without changing anything if any of the
+	// buffers concerned has changed since.
+	CommandRedoEdit Command = "RedoEdit"
+
 	// CommandStringFn applies a transformation function to text. Without a
 	// range the current line is used as input. Visual ranges can also be used,
 	// with the exception of visual blocks. The command takes one or more
@@ -282,6 +510,82 @@ const (
 	// CommandClearReferencesHighlights clears any highlighint of references
 	// added by a previous call to CommandHighlightReferences
 	CommandClearReferencesHighlights Command = "ClearReferencesHighlights"
+
+	// CommandWorkspaceSymbol opens a popup listing the symbols in the
+	// workspace that match the query given as an optional argument. Typing
+	// in the popup narrows the query (backspace widens it again); <C-n> and
+	// <C-p> move the selection. Selecting a symbol jumps to its location,
+	// pushing the current location onto the jump stack. CommandWorkspaceSymbol
+	// respects &switchbuf
+	CommandWorkspaceSymbol Command = "WorkspaceSymbol"
+
+	// CommandOutline toggles a side window that shows the outline (the
+	// nested symbols) of the current buffer. The cursor in the outline window
+	// follows the cursor in the outlined buffer, and the outline is updated
+	// as the buffer changes. Pressing <CR> on a symbol jumps to it, pushing
+	// the current location onto the jump stack. Command modifiers (e.g.
+	// :leftabove) control where the window is opened; the default is
+	// :vertical botright
+	CommandOutline Command = "Outline"
+
+	// CommandCallersOf opens a window showing the tree of callers of the
+	// function under the cursor. Pressing o on a function expands or
+	// collapses its callers, which are fetched on first expansion. Recursive
+	// calls are marked with @ and cannot be expanded. Pressing <CR> jumps to
+	// the call site, pushing the current location onto the jump stack.
+	// Requires a version of gopls that supports call hierarchies
+	CommandCallersOf Command = "CallersOf"
+
+	// CommandCalleesOf is the counterpart of CommandCallersOf, showing the
+	// tree of functions called by the function under the cursor
+	CommandCalleesOf Command = "CalleesOf"
+
+	// CommandCodeLens runs the code lens on the cursor line (see
+	// Config.CodeLens). If there is more than one lens on the line, the title
+	// of the lens to run must be given as an argument
+	CommandCodeLens Command = "CodeLens"
+
+	// CommandWorkspaceAdd adds the directories given as arguments (or the
+	// current working directory if none are given) as gopls workspace
+	// folders. Changing the current working directory to a directory outside
+	// of the existing workspace folders (see :help DirChanged) also adds a
+	// workspace folder
+	CommandWorkspaceAdd Command = "WorkspaceAdd"
+
+	// CommandWorkspaceRemove removes the workspace folders given as
+	// arguments
+	CommandWorkspaceRemove Command = "WorkspaceRemove"
+
+	// CommandGoplsRestart restarts gopls, e.g. if it has stopped responding
+	// or has been upgraded. The new gopls is told about all open buffers
+	CommandGoplsRestart Command = "GoplsRestart"
+
+	// CommandDiagnosticsFilter toggles the filtering of diagnostics by
+	// severity, source and path (see Config.DiagnosticsQuickfixSeverity and
+	// friends), without changing the filter config. An optional argument of
+	// "on" or "off" sets, rather than toggles, the filtering.
+	CommandDiagnosticsFilter Command = "DiagnosticsFilter"
+
+	// CommandNextDiagnostic moves the cursor to the next diagnostic in the
+	// current buffer, wrapping around at the end of the buffer, and shows it
+	// in the hover popup. An optional argument restricts the jump to
+	// diagnostics of at least the given severity, one of the values of type
+	// Severity, e.g.:
+	//
+	//     GOVIMNextDiagnostic error
+	//
+	// Source and path filters (see Config.DiagnosticsExcludeSources etc.)
+	// also apply.
+	CommandNextDiagnostic Command = "NextDiagnostic"
+
+	// CommandPrevDiagnostic is as CommandNextDiagnostic, but moves to the
+	// previous diagnostic, wrapping around at the start of the buffer
+	CommandPrevDiagnostic Command = "PrevDiagnostic"
+
+	// CommandHoverLink opens the link numbered by the optional argument
+	// (default 1) from the footer of the most recent hover popup, using
+	// netrw's gx handling
+	CommandHoverLink Command = "HoverLink"
 )
 
 type Function string
@@ -300,6 +604,26 @@ const (
 	// identifier.
 	FunctionHover Function = "Hover"
 
+	// FunctionProgress returns a short description of the progress of any
+	// long running gopls operations, or the empty string if there are none.
+	// It is intended for use in a statusline, e.g.:
+	//
+	// set statusline+=%{GOVIMProgress()}
+	FunctionProgress Function = "Progress"
+
+	// FunctionDiagnosticsCount returns a dictionary of the number of
+	// diagnostics by severity, keyed by "error", "warning", "info" and
+	// "hint". With no argument the counts are for the whole workspace;
+	// otherwise they are for the buffer with the given number, where 0 means
+	// the current buffer. The source and path filters of
+	// Config.DiagnosticsIncludeSources, Config.DiagnosticsExcludeSources and
+	// Config.DiagnosticsExcludePaths apply. It is intended for use in a
+	// statusline, along with the User GOVIMDiagnosticsChanged autocommand
+	// that is triggered whenever the diagnostics change, e.g.:
+	//
+	// autocmd User GOVIMDiagnosticsChanged redrawstatus!
+	FunctionDiagnosticsCount Function = "DiagnosticsCount"
+
 	// FunctionBufChanged is an internal function used by govim for handling
 	// delta-based changes in buffers.
 	FunctionBufChanged Function = InternalFunctionPrefix + "BufChanged"
@@ -322,8 +646,85 @@ const (
 	// completion of arguments to CommandStringFn
 	FunctionStringFnComplete Function = InternalFunctionPrefix + "StringFnComplete"
 
-	// FunctionMotion moves the cursor according to the arguments provided.
+	// FunctionMotion moves the cursor according to the arguments provided:
+	//
+	//     GOVIMMotion(direction, target)
+	//
+	// direction is "next" or "prev". target is Node.Pos() or Node.End() to
+	// move to the start or end of a node, where Node is one of File.Decls,
+	// FuncDecl (a function), MethodDecl (a method), TypeSpec, Field (a struct
+	// field), CaseClause (including select cases), IfStmt, ForStmt (including
+	// range statements), ReturnStmt or CommentGroup. For example:
+	//
+	//     nnoremap <buffer> <silent> ]f :call GOVIMMotion("next", "FuncDecl.Pos()")<cr>
 	FunctionMotion Function = "Motion"
+
+	// FunctionTextObject visually selects a Go-aware text object around the
+	// cursor, and is intended for use in mappings for Visual and
+	// Operator-pending modes:
+	//
+	//     GOVIMTextObject(object, extent)
+	//
+	// object is "function" (the innermost function declaration or literal)
+	// or "comment" (the comment group). extent is "inner" (the function body,
+	// or the comment text from after the first comment marker) or "around"
+	// (the whole function or comment, including any doc comment of a function
+	// declaration). Text objects are also found in files with syntax errors.
+	// Default mappings are provided for if/af and ic/ac.
+	FunctionTextObject Function = "TextObject"
+
+	// FunctionExpandSelection visually selects the smallest syntactic range
+	// that encloses the cursor. Called again whilst that range is (still)
+	// selected, it selects the next enclosing range, and so on. It is
+	// intended to be mapped, for example:
+	//
+	//     xnoremap <buffer> <silent> + :<C-u>call GOVIMExpandSelection()<cr>
+	//     nnoremap <buffer> <silent> + :call GOVIMExpandSelection()<cr>
+	FunctionExpandSelection Function = "ExpandSelection"
+
+	// FunctionShrinkSelection reverses the last FunctionExpandSelection,
+	// visually selecting the range that was selected before it. It is
+	// intended to be mapped, for example:
+	//
+	//     xnoremap <buffer> <silent> - :<C-u>call GOVIMShrinkSelection()<cr>
+	FunctionShrinkSelection Function = "ShrinkSelection"
+
+	// FunctionWorkspaceSymbolUpdate is an internal function used by govim to
+	// update the results in a CommandWorkspaceSymbol popup as the query
+	// changes
+	FunctionWorkspaceSymbolUpdate Function = InternalFunctionPrefix + "WorkspaceSymbolUpdate"
+
+	// FunctionOutlineJump is an internal function used by govim to jump to
+	// the symbol on a given line of the CommandOutline window
+	FunctionOutlineJump Function = InternalFunctionPrefix + "OutlineJump"
+
+	// FunctionCallHierarchyToggle is an internal function used by govim to
+	// expand or collapse a node in a CommandCallersOf or CommandCalleesOf
+	// window
+	FunctionCallHierarchyToggle Function = InternalFunctionPrefix + "CallHierarchyToggle"
+
+	// FunctionCallHierarchyJump is an internal function used by govim to
+	// jump to the call site of a node in a CommandCallersOf or
+	// CommandCalleesOf window
+	FunctionCallHierarchyJump Function = InternalFunctionPrefix + "CallHierarchyJump"
+
+	// FunctionRenamePreviewMark is an internal function used by govim to
+	// accept or reject the changes to the file on a given line of the
+	// Config.RenamePreview window
+	FunctionRenamePreviewMark Function = InternalFunctionPrefix + "RenamePreviewMark"
+
+	// FunctionRenamePreviewApply is an internal function used by govim to
+	// apply the accepted changes in the Config.RenamePreview window
+	FunctionRenamePreviewApply Function = InternalFunctionPrefix + "RenamePreviewApply"
+
+	// FunctionSnippetJump moves to the next (argument 1) or previous
+	// (argument -1) tab stop of the most recently completed snippet (see
+	// Config.CompletionSnippets). Placeholder text is selected in Select mode,
+	// so that typing replaces it. For example:
+	//
+	// inoremap <silent> <C-j> <C-o>:call GOVIMSnippetJump(1)<CR>
+	// snoremap <silent> <C-j> <Esc>:call GOVIMSnippetJump(1)<CR>
+	FunctionSnippetJump Function = "SnippetJump"
 )
 
 // FormatOnSave typed constants define the set of valid values that
@@ -367,6 +768,113 @@ const (
 	CompletionMatcherCaseInsensitive CompletionMatcher = "caseInsensitive"
 )
 
+// GoplsCrashPolicy typed constants define the set of valid values that
+// Config.GoplsCrashPolicy can take
+type GoplsCrashPolicy string
+
+const (
+	// GoplsCrashPolicyExit specifies that govim should report an error and
+	// stop when gopls exits unexpectedly
+	GoplsCrashPolicyExit GoplsCrashPolicy = "exit"
+
+	// GoplsCrashPolicyRestart specifies that govim should restart gopls when
+	// it exits unexpectedly. If gopls exits repeatedly in a short space of
+	// time, govim gives up and behaves as per GoplsCrashPolicyExit
+	GoplsCrashPolicyRestart GoplsCrashPolicy = "restart"
+)
+
+// GoToMultipleLocations typed constants define the set of valid values that
+// Config.GoToMultipleLocations can take
+type GoToMultipleLocations string
+
+const (
+	// GoToMultipleLocationsPopup specifies that the locations are listed in
+	// a popup menu, from which the target can be picked
+	GoToMultipleLocationsPopup GoToMultipleLocations = "popup"
+
+	// GoToMultipleLocationsLocationList specifies that the locations are
+	// used to populate the location list of the current window, which is
+	// then opened
+	GoToMultipleLocationsLocationList GoToMultipleLocations = "loclist"
+)
+
+// MultiFileEditStrategy typed constants define the set of valid values that
+// Config.MultiFileEditStrategy can take
+type MultiFileEditStrategy string
+
+const (
+	// MultiFileEditStrategyBufLoad specifies that files are loaded into
+	// hidden buffers, without opening any windows. The modified buffers then
+	// need to be written, for example with :wall
+	MultiFileEditStrategyBufLoad MultiFileEditStrategy = "bufload"
+
+	// MultiFileEditStrategySplit specifies that each file is opened in a
+	// new split window
+	MultiFileEditStrategySplit MultiFileEditStrategy = "split"
+
+	// MultiFileEditStrategyTab specifies that each file is opened in a new
+	// tab page
+	MultiFileEditStrategyTab MultiFileEditStrategy = "tab"
+)
+
+// FoldingRangeKind typed constants define the set of valid values that
+// the elements of Config.FoldingRangeKinds can take
+type FoldingRangeKind string
+
+const (
+	// FoldingRangeKindImports is the kind of the import block
+	FoldingRangeKindImports FoldingRangeKind = "imports"
+
+	// FoldingRangeKindComment is the kind of multi-line comments
+	FoldingRangeKindComment FoldingRangeKind = "comment"
+
+	// FoldingRangeKindFunction is the kind of function bodies, including
+	// those of function literals
+	FoldingRangeKindFunction FoldingRangeKind = "function"
+
+	// FoldingRangeKindComposite is the kind of composite literals
+	FoldingRangeKindComposite FoldingRangeKind = "composite"
+
+	// FoldingRangeKindOther is the kind of all other folding ranges, for
+	// example the blocks of if and for statements, call arguments and
+	// struct type fields
+	FoldingRangeKindOther FoldingRangeKind = "other"
+)
+
+// DiagnosticsList typed constants define the set of valid values that
+// Config.DiagnosticsList can take
+type DiagnosticsList string
+
+const (
+	// DiagnosticsListQuickfix specifies that the diagnostics for the whole
+	// workspace are listed in the quickfix list
+	DiagnosticsListQuickfix DiagnosticsList = "quickfix"
+
+	// DiagnosticsListLocationList specifies that the diagnostics for a
+	// buffer are listed in the location list of each window that shows the
+	// buffer. A location list that is already in use for something else is
+	// left alone.
+	DiagnosticsListLocationList DiagnosticsList = "loclist"
+)
+
+// Severity typed constants define the set of valid values that the
+// Config.Diagnostics*Severity options can take, from most to least severe
+type Severity string
+
+const (
+	// SeverityError is the severity of errors
+	SeverityError Severity = "error"
+
+	// SeverityWarning is the severity of warnings
+	SeverityWarning Severity = "warning"
+
+	// SeverityInfo is the severity of informational diagnostics
+	SeverityInfo Severity = "info"
+
+	// SeverityHint is the severity of hints
+	SeverityHint Severity = "hint"
+)
+
 // Highlight typed constants define the different highlight groups used by govim.
 // All highlights can be overridden in vimrc, e.g.:
 //
@@ -404,6 +912,83 @@ const (
 	// HighlightHoverDiagSrc is the group used to format the source part of a hover diagnostic
 	HighlightHoverDiagSrc Highlight = "GOVIMHoverDiagSrc"
 
+	// HighlightHoverHeading is the group used to highlight headings in the
+	// hover popup
+	HighlightHoverHeading Highlight = "GOVIMHoverHeading"
+	// HighlightHoverEmphasis is the group used to highlight emphasised text
+	// in the hover popup
+	HighlightHoverEmphasis Highlight = "GOVIMHoverEmphasis"
+	// HighlightHoverStrong is the group used to highlight strongly
+	// emphasised text in the hover popup
+	HighlightHoverStrong Highlight = "GOVIMHoverStrong"
+	// HighlightHoverCode is the group used to highlight inline code in the
+	// hover popup
+	HighlightHoverCode Highlight = "GOVIMHoverCode"
+	// HighlightHoverLink is the group used to highlight links in the hover
+	// popup
+	HighlightHoverLink Highlight = "GOVIMHoverLink"
+	// HighlightHoverCodeKeyword is the group used to highlight keywords in Go
+	// code blocks in the hover popup
+	HighlightHoverCodeKeyword Highlight = "GOVIMHoverCodeKeyword"
+	// HighlightHoverCodeType is the group used to highlight predeclared types
+	// in Go code blocks in the hover popup
+	HighlightHoverCodeType Highlight = "GOVIMHoverCodeType"
+	// HighlightHoverCodeString is the group used to highlight string and rune
+	// literals in Go code blocks in the hover popup
+	HighlightHoverCodeString Highlight = "GOVIMHoverCodeString"
+	// HighlightHoverCodeNumber is the group used to highlight number literals
+	// in Go code blocks in the hover popup
+	HighlightHoverCodeNumber Highlight = "GOVIMHoverCodeNumber"
+	// HighlightHoverCodeComment is the group used to highlight comments in Go
+	// code blocks in the hover popup
+	HighlightHoverCodeComment Highlight = "GOVIMHoverCodeComment"
+
+	// HighlightInlineErr is the group used to show errors inline
+	HighlightInlineErr Highlight = "GOVIMInlineErr"
+	// HighlightInlineWarn is the group used to show warnings inline
+	HighlightInlineWarn Highlight = "GOVIMInlineWarn"
+	// HighlightInlineInfo is the group used to show informations inline
+	HighlightInlineInfo Highlight = "GOVIMInlineInfo"
+	// HighlightInlineHint is the group used to show hints inline
+	HighlightInlineHint Highlight = "GOVIMInlineHint"
+
 	// HighlightReferences is the group used to add text properties to references
 	HighlightReferences Highlight = "GOVIMReferences"
+
+	// HighlightSignatureActiveParameter is the group used to highlight the
+	// active parameter in the signature help popup
+	HighlightSignatureActiveParameter Highlight = "GOVIMSignatureActiveParameter"
+
+	// HighlightCodeLens is the group used to show code lenses
+	HighlightCodeLens Highlight = "GOVIMCodeLens"
+
+	// HighlightSnippetPlaceholder is the group used to mark the tab stops of
+	// an expanded completion snippet
+	HighlightSnippetPlaceholder Highlight = "GOVIMSnippetPlaceholder"
+
+	// HighlightSemNamespace is the group used to highlight package names
+	HighlightSemNamespace Highlight = "GOVIMSemNamespace"
+	// HighlightSemType is the group used to highlight types
+	HighlightSemType Highlight = "GOVIMSemType"
+	// HighlightSemParameter is the group used to highlight function
+	// parameters and results
+	HighlightSemParameter Highlight = "GOVIMSemParameter"
+	// HighlightSemVariable is the group used to highlight variables
+	HighlightSemVariable Highlight = "GOVIMSemVariable"
+	// HighlightSemProperty is the group used to highlight struct fields
+	HighlightSemProperty Highlight = "GOVIMSemProperty"
+	// HighlightSemFunction is the group used to highlight functions
+	HighlightSemFunction Highlight = "GOVIMSemFunction"
+	// HighlightSemMethod is the group used to highlight methods
+	HighlightSemMethod Highlight = "GOVIMSemMethod"
+	// HighlightSemReadonly is the group used to highlight read-only
+	// identifiers, i.e. constants. It is combined with the group of the
+	// identifier's type
+	HighlightSemReadonly Highlight = "GOVIMSemReadonly"
+	// HighlightSemDefaultLibrary is the group used to highlight predeclared
+	// identifiers. It is combined with the group of the identifier's type
+	HighlightSemDefaultLibrary Highlight = "GOVIMSemDefaultLibrary"
+	// HighlightSemDeprecated is the group used to highlight deprecated
+	// identifiers. It is combined with the group of the identifier's type
+	HighlightSemDeprecated Highlight = "GOVIMSemDeprecated"
 )
@@ -7,6 +7,12 @@ func (r *Config) Apply(v *Config) {
 	if v.QuickfixAutoDiagnostics != nil {
 		r.QuickfixAutoDiagnostics = v.QuickfixAutoDiagnostics
 	}
+	if v.DiagnosticsList != nil {
+		r.DiagnosticsList = v.DiagnosticsList
+	}
+	if v.InlineDiagnostics != nil {
+		r.InlineDiagnostics = v.InlineDiagnostics
+	}
 	if v.QuickfixSigns != nil {
 		r.QuickfixSigns = v.QuickfixSigns
 	}
@@ -55,4 +61,70 @@ func (r *Config) Apply(v *Config) {
 	if v.ExperimentalWorkaroundCompleteoptLongest != nil {
 		r.ExperimentalWorkaroundCompleteoptLongest = v.ExperimentalWorkaroundCompleteoptLongest
 	}
+	if v.SignatureHelp != nil {
+		r.SignatureHelp = v.SignatureHelp
+	}
+	if v.ExperimentalSignatureHelpPopupOptions != nil {
+		r.ExperimentalSignatureHelpPopupOptions = v.ExperimentalSignatureHelpPopupOptions
+	}
+	if v.CodeLens != nil {
+		r.CodeLens = v.CodeLens
+	}
+	if v.ProgressPopup != nil {
+		r.ProgressPopup = v.ProgressPopup
+	}
+	if v.ShowMessageRequestTimeout != nil {
+		r.ShowMessageRequestTimeout = v.ShowMessageRequestTimeout
+	}
+	if v.GoplsCrashPolicy != nil {
+		r.GoplsCrashPolicy = v.GoplsCrashPolicy
+	}
+	if v.CompletionSnippets != nil {
+		r.CompletionSnippets = v.CompletionSnippets
+	}
+	if v.CompletionAsync != nil {
+		r.CompletionAsync = v.CompletionAsync
+	}
+	if v.GoToMultipleLocations != nil {
+		r.GoToMultipleLocations = v.GoToMultipleLocations
+	}
+	if v.RenamePreview != nil {
+		r.RenamePreview = v.RenamePreview
+	}
+	if v.MultiFileEditStrategy != nil {
+		r.MultiFileEditStrategy = v.MultiFileEditStrategy
+	}
+	if v.FoldingRanges != nil {
+		r.FoldingRanges = v.FoldingRanges
+	}
+	if v.FoldingRangeKinds != nil {
+		r.FoldingRangeKinds = v.FoldingRangeKinds
+	}
+	if v.SemanticHighlighting != nil {
+		r.SemanticHighlighting = v.SemanticHighlighting
+	}
+	if v.DiagnosticsQuickfixSeverity != nil {
+		r.DiagnosticsQuickfixSeverity = v.DiagnosticsQuickfixSeverity
+	}
+	if v.DiagnosticsSignsSeverity != nil {
+		r.DiagnosticsSignsSeverity = v.DiagnosticsSignsSeverity
+	}
+	if v.DiagnosticsHighlightSeverity != nil {
+		r.DiagnosticsHighlightSeverity = v.DiagnosticsHighlightSeverity
+	}
+	if v.DiagnosticsHoverSeverity != nil {
+		r.DiagnosticsHoverSeverity = v.DiagnosticsHoverSeverity
+	}
+	if v.DiagnosticsInlineSeverity != nil {
+		r.DiagnosticsInlineSeverity = v.DiagnosticsInlineSeverity
+	}
+	if v.DiagnosticsIncludeSources != nil {
+		r.DiagnosticsIncludeSources = v.DiagnosticsIncludeSources
+	}
+	if v.DiagnosticsExcludeSources != nil {
+		r.DiagnosticsExcludeSources = v.DiagnosticsExcludeSources
+	}
+	if v.DiagnosticsExcludePaths != nil {
+		r.DiagnosticsExcludePaths = v.DiagnosticsExcludePaths
+	}
 }
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/govim/govim"
+	"github.com/govim/govim/cmd/govim/config"
+	"github.com/govim/govim/cmd/govim/internal/types"
+)
+
+func (v *vimstate) nextDiagnostic(flags govim.CommandFlags, args ...string) error {
+	return v.jumpToDiagnostic(true, args...)
+}
+
+func (v *vimstate) prevDiagnostic(flags govim.CommandFlags, args ...string) error {
+	return v.jumpToDiagnostic(false, args...)
+}
+
+// jumpToDiagnostic moves the cursor to the next (or previous) diagnostic in
+// the current buffer that is at least as severe as the optional severity
+// argument, wrapping around at the end (or start) of the buffer. The
+// diagnostic is then shown in the hover popup.
+func (v *vimstate) jumpToDiagnostic(next bool, args ...string) error {
+	min := config.SeverityHint
+	if len(args) == 1 {
+		min = config.Severity(args[0])
+		if _, ok := types.SeverityFromConfig[min]; !ok {
+			return fmt.Errorf("got unknown severity %q", args[0])
+		}
+	}
+	b, pos, err := v.cursorPos()
+	if err != nil {
+		return fmt.Errorf("failed to get current position: %v", err)
+	}
+	// The severity argument applies even if filtering is turned off, hence
+	// we check it here rather than via filterDiagnostics
+	maxSeverity := types.SeverityFromConfig[min]
+	var diags []types.Diagnostic
+	for _, d := range v.filterDiagnostics(*v.diagnostics(), nil) {
+		// LSP severities are ordered from most (1) to least severe
+		if d.Buf == b.Num && d.Severity <= maxSeverity {
+			diags = append(diags, d)
+		}
+	}
+	if len(diags) == 0 {
+		v.ChannelEx(`echom "No diagnostics"`)
+		return nil
+	}
+
+	// diagnostics() are sorted by position
+	before := func(d types.Diagnostic) bool {
+		s := d.Range.Start
+		return s.Line() < pos.Line() || s.Line() == pos.Line() && s.Col() < pos.Col()
+	}
+	after := func(d types.Diagnostic) bool {
+		s := d.Range.Start
+		return s.Line() > pos.Line() || s.Line() == pos.Line() && s.Col() > pos.Col()
+	}
+	var target *types.Diagnostic
+	if next {
+		for i := range diags {
+			if after(diags[i]) {
+				target = &diags[i]
+				break
+			}
+		}
+		if target == nil {
+			target = &diags[0]
+			v.ChannelEx(`echom "Wrapped to first diagnostic"`)
+		}
+	} else {
+		for i := len(diags) - 1; i >= 0; i-- {
+			if before(diags[i]) {
+				target = &diags[i]
+				break
+			}
+		}
+		if target == nil {
+			target = &diags[len(diags)-1]
+			v.ChannelEx(`echom "Wrapped to last diagnostic"`)
+		}
+	}
+
+	v.ChannelEx("normal! m'")
+	v.ChannelCall("cursor", target.Range.Start.Line(), target.Range.Start.Col())
+	// Ensure the cursor is on screen before we position the popup
+	v.ChannelRedraw(false)
+	_, err = v.hover()
+	return err
+}
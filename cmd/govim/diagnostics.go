@@ -97,5 +97,11 @@ func (v *vimstate) handleDiagnosticsChanged() error {
 	if err := v.redefineHighlights(false); err != nil {
 		v.Logf("redefineDiagnostics: failed to apply highlights: %v", err)
 	}
+
+	if err := v.updateInlineDiagnostics(false); err != nil {
+		v.Logf("redefineDiagnostics: failed to update inline diagnostics: %v", err)
+	}
+
+	v.fireDiagnosticsChanged()
 	return nil
 }
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/govim/govim/cmd/govim/config"
+	"github.com/govim/govim/cmd/govim/internal/types"
+)
+
+// diagnosticsCount is the implementation of config.FunctionDiagnosticsCount.
+// It returns the number of diagnostics by severity, either for the whole
+// workspace or for the buffer given by the optional bufnr argument.
+func (v *vimstate) diagnosticsCount(args ...json.RawMessage) (interface{}, error) {
+	if len(args) > 1 {
+		return nil, fmt.Errorf("expected at most one argument; got %v", len(args))
+	}
+	bufnr := -1
+	if len(args) == 1 {
+		bufnr = v.ParseInt(args[0])
+		if bufnr == 0 {
+			bufnr = v.ParseInt(v.ChannelExpr(`bufnr("")`))
+		}
+	}
+	// must be non-nil, with all severities present
+	res := map[config.Severity]int{
+		config.SeverityError:   0,
+		config.SeverityWarning: 0,
+		config.SeverityInfo:    0,
+		config.SeverityHint:    0,
+	}
+	for _, d := range v.filterDiagnostics(*v.diagnostics(), nil) {
+		if bufnr != -1 && d.Buf != bufnr {
+			continue
+		}
+		for s, ts := range types.SeverityFromConfig {
+			if d.Severity == ts {
+				res[s]++
+				break
+			}
+		}
+	}
+	return res, nil
+}
+
+// fireDiagnosticsChanged triggers the User GOVIMDiagnosticsChanged
+// autocommand, if any are defined, when the diagnostics have changed since it
+// was last triggered
+func (v *vimstate) fireDiagnosticsChanged() {
+	diagsRef := v.diagnostics()
+	if v.lastDiagnosticsAutocmd == diagsRef {
+		return
+	}
+	v.lastDiagnosticsAutocmd = diagsRef
+	v.ChannelEx(`if exists("#User#GOVIMDiagnosticsChanged") | doautocmd <nomodeline> User GOVIMDiagnosticsChanged | endif`)
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/govim/govim"
+	"github.com/govim/govim/cmd/govim/config"
+	"github.com/govim/govim/cmd/govim/internal/types"
+)
+
+// filterDiagnostics returns the diagnostics in diags that are at least as
+// severe as minSeverity, and which pass the source and path filters in
+// config. diags is returned as is if filtering is off.
+func (v *vimstate) filterDiagnostics(diags []types.Diagnostic, minSeverity *config.Severity) []types.Diagnostic {
+	if v.diagnosticsFilterOff {
+		return diags
+	}
+	min := types.SeverityHint
+	if minSeverity != nil {
+		if s, ok := types.SeverityFromConfig[*minSeverity]; ok {
+			min = s
+		}
+	}
+	var include, exclude, excludePaths []string
+	if v.config.DiagnosticsIncludeSources != nil {
+		include = *v.config.DiagnosticsIncludeSources
+	}
+	if v.config.DiagnosticsExcludeSources != nil {
+		exclude = *v.config.DiagnosticsExcludeSources
+	}
+	if v.config.DiagnosticsExcludePaths != nil {
+		excludePaths = *v.config.DiagnosticsExcludePaths
+	}
+	if min == types.SeverityHint && len(include) == 0 && len(exclude) == 0 && len(excludePaths) == 0 {
+		return diags
+	}
+
+	// must be non-nil
+	res := []types.Diagnostic{}
+	for _, d := range diags {
+		// LSP severities are ordered from most (1) to least severe
+		if d.Severity > min {
+			continue
+		}
+		if len(include) > 0 && !containsString(include, d.Source) {
+			continue
+		}
+		if containsString(exclude, d.Source) {
+			continue
+		}
+		if v.excludedPath(excludePaths, d.Filename) {
+			continue
+		}
+		res = append(res, d)
+	}
+	return res
+}
+
+// excludedPath reports whether fn matches any of the glob patterns. See
+// config.Config.DiagnosticsExcludePaths for details of how the patterns
+// match.
+func (v *vimstate) excludedPath(patterns []string, fn string) bool {
+	if len(patterns) == 0 {
+		return false
+	}
+	rel, err := filepath.Rel(v.workingDirectory, fn)
+	if err != nil {
+		rel = fn
+	}
+	rel = filepath.ToSlash(rel)
+	for _, p := range patterns {
+		if !strings.Contains(p, "/") {
+			if ok, _ := path.Match(p, path.Base(rel)); ok {
+				return true
+			}
+			continue
+		}
+		if matchGlob(strings.Split(p, "/"), strings.Split(rel, "/")) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchGlob reports whether the path elements elems match the pattern
+// elements pats, where a ** pattern element matches zero or more path
+// elements
+func matchGlob(pats, elems []string) bool {
+	for len(pats) > 0 {
+		if pats[0] == "**" {
+			for i := 0; i <= len(elems); i++ {
+				if matchGlob(pats[1:], elems[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(elems) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pats[0], elems[0]); !ok {
+			return false
+		}
+		pats, elems = pats[1:], elems[1:]
+	}
+	return len(elems) == 0
+}
+
+func containsString(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
+
+func (v *vimstate) diagnosticsFilter(flags govim.CommandFlags, args ...string) error {
+	off := !v.diagnosticsFilterOff
+	if len(args) == 1 {
+		switch args[0] {
+		case "on":
+			off = false
+		case "off":
+			off = true
+		default:
+			return fmt.Errorf("got unknown argument %q; expected on or off", args[0])
+		}
+	}
+	v.diagnosticsFilterOff = off
+	if off {
+		v.ChannelEx(`echom "Diagnostics filter off"`)
+	} else {
+		v.ChannelEx(`echom "Diagnostics filter on"`)
+	}
+	return v.redisplayDiagnostics()
+}
+
+// redisplayDiagnostics updates all consumers of diagnostics, e.g. after the
+// filters that apply to them have changed
+func (v *vimstate) redisplayDiagnostics() error {
+	v.lastDiagnosticsQuickfix = nil
+	v.lastDiagnosticsLocationLists = nil
+	v.lastDiagnosticsSigns = nil
+	v.lastDiagnosticsHighlights = nil
+	v.lastDiagnosticsInline = nil
+	v.lastDiagnosticsAutocmd = nil
+	return v.handleDiagnosticsChanged()
+}
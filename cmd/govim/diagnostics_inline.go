@@ -0,0 +1,167 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/govim/govim/cmd/govim/internal/types"
+)
+
+// inlineDiagnosticMaxLen is the maximum length, in runes, of an inline
+// diagnostic message, beyond which it is truncated
+const inlineDiagnosticMaxLen = 80
+
+// exprInlineDiagnosticsPos is the expression that gives the buffer and line
+// of the cursor, as used to hide an inline diagnostic in insert mode
+const exprInlineDiagnosticsPos = `{"bufnr": bufnr(""), "line": line(".")}`
+
+// inlineDiagnosticsLine identifies a line of a buffer
+type inlineDiagnosticsLine struct {
+	buf  int
+	line int
+}
+
+type inlineDiagnosticsPos struct {
+	BufNr int `json:"bufnr"`
+	Line  int `json:"line"`
+}
+
+// inlineDiagnosticsEnabled reports whether diagnostics should be shown inline
+func (v *vimstate) inlineDiagnosticsEnabled() bool {
+	return v.config.InlineDiagnostics != nil && *v.config.InlineDiagnostics && v.hasTextPropText
+}
+
+// updateInlineDiagnostics ensures that Vim is updated with the diagnostic
+// messages shown after the end of the lines to which they apply
+func (v *vimstate) updateInlineDiagnostics(force bool) error {
+	if !v.inlineDiagnosticsEnabled() {
+		return nil
+	}
+	diagsRef := v.diagnostics()
+	work := v.lastDiagnosticsInline != diagsRef
+	v.lastDiagnosticsInline = diagsRef
+	if !force && !work {
+		return nil
+	}
+	diags := v.filterDiagnostics(*diagsRef, v.config.DiagnosticsInlineSeverity)
+
+	// Find the most severe diagnostic for each line, along with the number
+	// of others on that line. Diagnostics are sorted by position, hence the
+	// first of a given severity on a line wins.
+	type lineDiag struct {
+		diag  types.Diagnostic
+		count int
+	}
+	var keys []inlineDiagnosticsLine
+	lines := make(map[inlineDiagnosticsLine]*lineDiag)
+	for _, d := range diags {
+		if d.Buf < 0 {
+			continue
+		}
+		if buf, ok := v.buffers[d.Buf]; ok && !buf.Loaded {
+			continue
+		}
+		k := inlineDiagnosticsLine{buf: d.Buf, line: d.Range.Start.Line()}
+		if k == v.inlineDiagnosticsHidden {
+			continue
+		}
+		ld, ok := lines[k]
+		if !ok {
+			keys = append(keys, k)
+			lines[k] = &lineDiag{diag: d, count: 1}
+			continue
+		}
+		ld.count++
+		// LSP severities are ordered from most (1) to least severe
+		if d.Severity < ld.diag.Severity {
+			ld.diag = d
+		}
+	}
+
+	v.BatchStart()
+	defer v.BatchCancelIfNotEnded()
+	v.removeInlineDiagnostics()
+	for _, k := range keys {
+		ld := lines[k]
+		hi, ok := types.SeverityInlineHighlight[ld.diag.Severity]
+		if !ok {
+			return fmt.Errorf("failed to find inline highlight for severity %v", ld.diag.Severity)
+		}
+		text := inlineDiagnosticText(ld.diag.Text)
+		if ld.count > 1 {
+			text += fmt.Sprintf(" (+%d)", ld.count-1)
+		}
+		v.BatchAssertChannelCall(assertPropAdd, "prop_add", k.line, 0, struct {
+			Type      string `json:"type"`
+			Text      string `json:"text"`
+			TextAlign string `json:"text_align"`
+			BufNr     int    `json:"bufnr"`
+		}{string(hi), "  " + text, "after", k.buf})
+	}
+	v.MustBatchEnd()
+	return nil
+}
+
+// inlineDiagnosticText returns the first line of msg, truncated to
+// inlineDiagnosticMaxLen runes
+func inlineDiagnosticText(msg string) string {
+	if i := strings.IndexByte(msg, '\n'); i >= 0 {
+		msg = msg[:i]
+	}
+	if utf8.RuneCountInString(msg) <= inlineDiagnosticMaxLen {
+		return msg
+	}
+	return string([]rune(msg)[:inlineDiagnosticMaxLen-1]) + "…"
+}
+
+// removeInlineDiagnostics removes all inline diagnostics. Text properties
+// with text are assigned their own IDs by Vim, hence we remove them by type.
+func (v *vimstate) removeInlineDiagnostics() {
+	var didStart bool
+	if didStart = v.BatchStartIfNeeded(); didStart {
+		defer v.BatchCancelIfNotEnded()
+	}
+	for bufnr, buf := range v.buffers {
+		if !buf.Loaded {
+			continue // vim removes properties when a buffer is unloaded
+		}
+		for _, hi := range types.SeverityInlineHighlight {
+			v.BatchChannelCall("prop_remove", struct {
+				Type  string `json:"type"`
+				BufNr int    `json:"bufnr"`
+				All   int    `json:"all"`
+			}{string(hi), bufnr, 1})
+		}
+	}
+	if didStart {
+		v.MustBatchEnd()
+	}
+}
+
+// inlineDiagnosticsCursorMovedI hides the inline diagnostic, if any, on the
+// cursor line in insert mode
+func (v *vimstate) inlineDiagnosticsCursorMovedI(args ...json.RawMessage) error {
+	if !v.inlineDiagnosticsEnabled() {
+		return nil
+	}
+	var pos inlineDiagnosticsPos
+	v.Parse(args[0], &pos)
+	hidden := inlineDiagnosticsLine{buf: pos.BufNr, line: pos.Line}
+	if hidden == v.inlineDiagnosticsHidden {
+		return nil
+	}
+	v.inlineDiagnosticsHidden = hidden
+	return v.updateInlineDiagnostics(true)
+}
+
+// inlineDiagnosticsInsertLeave shows the inline diagnostic, if any, that was
+// hidden on the cursor line in insert mode
+func (v *vimstate) inlineDiagnosticsInsertLeave(args ...json.RawMessage) error {
+	if v.inlineDiagnosticsHidden.buf == 0 {
+		return nil
+	}
+	v.inlineDiagnosticsHidden = inlineDiagnosticsLine{}
+	return v.updateInlineDiagnostics(true)
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/json"
+	"path/filepath"
+
+	"github.com/govim/govim/cmd/govim/config"
+)
+
+// diagnosticsLocListContext is the title and context of the location lists
+// that govim populates with diagnostics. The context identifies a location
+// list as belonging to govim, such that location lists that are in use for
+// something else are left alone.
+const diagnosticsLocListContext = "govim diagnostics"
+
+// exprDiagnosticsWindows is the expression that returns the normal (i.e. not
+// quickfix or location list) windows, along with the state of their location
+// lists
+const exprDiagnosticsWindows = `map(filter(getwininfo(), {_, w -> !w.quickfix}), {_, w -> {"winid": w.winid, "bufnr": w.bufnr, "loclist": getloclist(w.winid, {"context": 0, "idx": 0, "items": 0, "title": 0})}})`
+
+type diagnosticsWindow struct {
+	WinID   int `json:"winid"`
+	BufNr   int `json:"bufnr"`
+	LocList struct {
+		Context interface{} `json:"context"`
+		Idx     int         `json:"idx"`
+		Title   string      `json:"title"`
+		Items   []struct {
+			Lnum int    `json:"lnum"`
+			Col  int    `json:"col"`
+			Text string `json:"text"`
+		} `json:"items"`
+	} `json:"loclist"`
+}
+
+// diagnosticsInLocationLists reports whether diagnostics are listed in
+// per-window location lists rather than the quickfix list
+func (v *vimstate) diagnosticsInLocationLists() bool {
+	return v.config.DiagnosticsList != nil && *v.config.DiagnosticsList == config.DiagnosticsListLocationList
+}
+
+// updateLocationListsWithDiagnostics updates the location list of each window
+// with the current diagnostics() for its buffer. As with the quickfix list,
+// the selected entry is retained where it still exists.
+func (v *vimstate) updateLocationListsWithDiagnostics(force bool) error {
+	diagsRef := v.diagnostics()
+	work := v.lastDiagnosticsLocationLists != diagsRef
+	v.lastDiagnosticsLocationLists = diagsRef
+	if !force && !work {
+		return nil
+	}
+	diags := v.filterDiagnostics(*diagsRef, v.config.DiagnosticsQuickfixSeverity)
+
+	// must be non-nil values
+	entries := make(map[int][]quickfixEntry)
+	for _, d := range diags {
+		if d.Buf < 0 {
+			continue
+		}
+		fn, err := filepath.Rel(v.workingDirectory, d.Filename)
+		if err != nil {
+			fn = d.Filename
+		}
+		entries[d.Buf] = append(entries[d.Buf], quickfixEntry{
+			Filename: fn,
+			Lnum:     d.Range.Start.Line(),
+			Col:      d.Range.Start.Col(),
+			Text:     d.Text,
+			Buf:      d.Buf,
+		})
+	}
+
+	var wins []diagnosticsWindow
+	v.Parse(v.ChannelExpr(exprDiagnosticsWindows), &wins)
+	v.BatchStart()
+	defer v.BatchCancelIfNotEnded()
+	for _, w := range wins {
+		ours := w.LocList.Context == diagnosticsLocListContext
+		fixes, ok := entries[w.BufNr]
+		if !ours && (len(w.LocList.Items) > 0 || w.LocList.Title != "" || !ok) {
+			// Either the location list is in use for something else, or
+			// there is nothing to list
+			continue
+		}
+		if fixes == nil {
+			fixes = []quickfixEntry{}
+		}
+		// Note: indexes are 1-based, hence 0 means "no index"
+		newIdx := 0
+		if ours && w.LocList.Idx > 0 && w.LocList.Idx <= len(w.LocList.Items) {
+			curr := w.LocList.Items[w.LocList.Idx-1]
+			for i, f := range fixes {
+				if f.Lnum == curr.Lnum && f.Col == curr.Col && f.Text == curr.Text {
+					newIdx = i + 1
+					break
+				}
+			}
+		}
+		what := map[string]interface{}{
+			"items":   fixes,
+			"title":   diagnosticsLocListContext,
+			"context": diagnosticsLocListContext,
+		}
+		v.BatchChannelCall("setloclist", w.WinID, []quickfixEntry{}, "r", what)
+		if newIdx > 0 {
+			v.BatchChannelCall("setloclist", w.WinID, []quickfixEntry{}, "r", qflistWant{Idx: newIdx})
+		}
+	}
+	v.MustBatchEnd()
+	return nil
+}
+
+// clearDiagnosticsLocationLists empties the location lists that govim has
+// populated with diagnostics
+func (v *vimstate) clearDiagnosticsLocationLists() {
+	v.lastDiagnosticsLocationLists = nil
+	var wins []diagnosticsWindow
+	v.Parse(v.ChannelExpr(exprDiagnosticsWindows), &wins)
+	v.BatchStart()
+	defer v.BatchCancelIfNotEnded()
+	for _, w := range wins {
+		if w.LocList.Context != diagnosticsLocListContext {
+			continue
+		}
+		v.BatchChannelCall("setloclist", w.WinID, []quickfixEntry{}, "r", map[string]interface{}{
+			"items":   []quickfixEntry{},
+			"title":   "",
+			"context": "",
+		})
+	}
+	v.MustBatchEnd()
+}
+
+// diagnosticsBufWinEnter ensures that the location list of a window is
+// updated when a buffer is shown in it, e.g. a new split window
+func (v *vimstate) diagnosticsBufWinEnter(args ...json.RawMessage) error {
+	if !v.diagnosticsInLocationLists() || v.config.QuickfixAutoDiagnostics == nil || !*v.config.QuickfixAutoDiagnostics {
+		return nil
+	}
+	return v.updateLocationListsWithDiagnostics(true)
+}
+
+// setDiagnosticsList handles a change of config.Config.DiagnosticsList,
+// moving the diagnostics from one kind of list to the other
+func (v *vimstate) setDiagnosticsList() error {
+	if v.diagnosticsInLocationLists() {
+		if v.quickfixIsDiagnostics && len(v.lastQuickFixDiagnostics) > 0 {
+			v.lastQuickFixDiagnostics = []quickfixEntry{}
+			v.ChannelCall("setqflist", v.lastQuickFixDiagnostics, "r")
+		}
+	} else {
+		v.clearDiagnosticsLocationLists()
+	}
+	v.lastDiagnosticsQuickfix = nil
+	return v.updateQuickfixWithDiagnostics(false, false)
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/govim/govim"
+	"github.com/govim/govim/cmd/govim/internal/golang_org_x_tools/lsp/protocol"
+	"github.com/govim/govim/cmd/govim/internal/types"
+)
+
+// maxEditHistory is the maximum number of edit transactions that are
+// remembered for the purposes of config.CommandUndoEdit
+const maxEditHistory = 50
+
+// editTransaction records the changes made to buffers by a workspace edit
+// that govim applied, such that they can be undone and redone as one
+type editTransaction struct {
+	bufs []*editTransactionBuffer
+}
+
+// editTransactionBuffer records the change made to a single buffer as part of
+// an editTransaction
+type editTransactionBuffer struct {
+	bufnr int
+	name  string
+
+	// before and after are the buffer contents before and after the edit
+	before []byte
+	after  []byte
+
+	// version is the version of the buffer after the edit was most recently
+	// applied, undone or redone. If the buffer is no longer at this version
+	// it has been changed since, and the edit can no longer be undone or
+	// redone.
+	version int
+}
+
+// record records that b has been changed from before as part of t
+func (t *editTransaction) record(b *types.Buffer, before []byte) {
+	for _, tb := range t.bufs {
+		if tb.bufnr == b.Num {
+			tb.after = b.Contents()
+			tb.version = b.Version
+			return
+		}
+	}
+	t.bufs = append(t.bufs, &editTransactionBuffer{
+		bufnr:   b.Num,
+		name:    b.Name,
+		before:  before,
+		after:   b.Contents(),
+		version: b.Version,
+	})
+}
+
+// startEditTransaction starts recording the changes made via
+// applyMultiBufTextedits as a single transaction, returning a function that
+// ends the transaction and adds it to the edit history. Transactions do not
+// nest: if a transaction is already in progress, changes are recorded as part
+// of that transaction.
+func (v *vimstate) startEditTransaction() func() {
+	if v.editTransaction != nil {
+		return func() {}
+	}
+	txn := &editTransaction{}
+	v.editTransaction = txn
+	return func() {
+		v.editTransaction = nil
+		if len(txn.bufs) == 0 {
+			return
+		}
+		v.editHistory = append(v.editHistory[:v.editHistoryPos], txn)
+		if len(v.editHistory) > maxEditHistory {
+			v.editHistory = v.editHistory[len(v.editHistory)-maxEditHistory:]
+		}
+		v.editHistoryPos = len(v.editHistory)
+	}
+}
+
+// undoEdit is the implementation of config.CommandUndoEdit
+func (v *vimstate) undoEdit(flags govim.CommandFlags, args ...string) error {
+	if v.editHistoryPos == 0 {
+		v.ChannelEx(`echom "Already at oldest edit"`)
+		return nil
+	}
+	if err := v.revertEditTransaction(v.editHistory[v.editHistoryPos-1], true); err != nil {
+		return err
+	}
+	v.editHistoryPos--
+	return nil
+}
+
+// redoEdit is the implementation of config.CommandRedoEdit
+func (v *vimstate) redoEdit(flags govim.CommandFlags, args ...string) error {
+	if v.editHistoryPos == len(v.editHistory) {
+		v.ChannelEx(`echom "Already at newest edit"`)
+		return nil
+	}
+	if err := v.revertEditTransaction(v.editHistory[v.editHistoryPos], false); err != nil {
+		return err
+	}
+	v.editHistoryPos++
+	return nil
+}
+
+// revertEditTransaction sets each buffer changed by txn to its contents
+// before (undo is true) or after txn. No buffer is changed unless all the
+// buffers are unchanged since txn was last applied, undone or redone.
+func (v *vimstate) revertEditTransaction(txn *editTransaction, undo bool) error {
+	bufs := make([]*types.Buffer, len(txn.bufs))
+	for i, tb := range txn.bufs {
+		b, ok := v.buffers[tb.bufnr]
+		if !ok || !b.Loaded {
+			return fmt.Errorf("buffer %v (%v) is no longer loaded", tb.name, tb.bufnr)
+		}
+		if b.Version != tb.version {
+			return fmt.Errorf("buffer %v (%v) has changed since the edit", tb.name, tb.bufnr)
+		}
+		bufs[i] = b
+	}
+	for i, tb := range txn.bufs {
+		to := tb.after
+		if undo {
+			to = tb.before
+		}
+		if err := v.setBufferContents(bufs[i], to); err != nil {
+			return fmt.Errorf("failed to update buffer %v (%v): %v", tb.name, tb.bufnr, err)
+		}
+		tb.version = bufs[i].Version
+	}
+	return nil
+}
+
+// setBufferContents sets the contents of b to contents. Only the lines that
+// differ are replaced.
+func (v *vimstate) setBufferContents(b *types.Buffer, contents []byte) error {
+	curr := b.Contents()
+	if bytes.Equal(curr, contents) {
+		return nil
+	}
+	// Both end in a newline, hence the last element of each is empty
+	old := bytes.SplitAfter(curr, []byte("\n"))
+	want := bytes.SplitAfter(contents, []byte("\n"))
+	pre := 0
+	for pre < len(old)-1 && pre < len(want)-1 && bytes.Equal(old[pre], want[pre]) {
+		pre++
+	}
+	suf := 0
+	for suf < len(old)-1-pre && suf < len(want)-1-pre && bytes.Equal(old[len(old)-2-suf], want[len(want)-2-suf]) {
+		suf++
+	}
+	edit := protocol.TextEdit{
+		Range: protocol.Range{
+			Start: protocol.Position{Line: float64(pre)},
+			End:   protocol.Position{Line: float64(len(old) - 1 - suf)},
+		},
+		NewText: string(bytes.Join(want[pre:len(want)-1-suf], nil)),
+	}
+	return v.applyProtocolTextEdits(b, []protocol.TextEdit{edit})
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"sort"
+
+	"github.com/govim/govim"
+	"github.com/govim/govim/cmd/govim/config"
+	"github.com/govim/govim/cmd/govim/internal/golang_org_x_tools/lsp/protocol"
+	"github.com/govim/govim/cmd/govim/internal/types"
+)
+
+// foldLevelsVar is the buffer-local variable in which the fold level of each
+// line is stored for use by GOVIMFoldExpr()
+const foldLevelsVar = "govim_fold_levels"
+
+// bufFoldingRanges are the folding ranges for a buffer
+type bufFoldingRanges struct {
+	// version is the buffer version for which ranges were fetched
+	version int
+
+	// ranges are the classified folding ranges, sorted by start line
+	ranges []foldingRange
+
+	// cancel cancels the in-flight foldingRange request for the buffer, if
+	// any. It must only be used on the Vim "thread"
+	cancel context.CancelFunc
+}
+
+// foldingRange is a (line-based) folding range along with the kind used to
+// decide whether it folds (see config.Config.FoldingRangeKinds). Lines are
+// 1-indexed
+type foldingRange struct {
+	start int
+	end   int
+	kind  config.FoldingRangeKind
+}
+
+func (v *vimstate) foldingRangesEnabled() bool {
+	return v.config.FoldingRanges != nil && *v.config.FoldingRanges
+}
+
+// updateFoldingRanges (re)fetches the folding ranges for b if they are out
+// of date. It is called when a buffer is loaded and whenever it changes.
+func (v *vimstate) updateFoldingRanges(b *types.Buffer) {
+	if !v.foldingRangesEnabled() || !b.Loaded {
+		return
+	}
+	fr := v.foldingRanges[b.Num]
+	if fr == nil {
+		fr = &bufFoldingRanges{version: -1}
+		v.foldingRanges[b.Num] = fr
+	}
+	if fr.version == b.Version {
+		return
+	}
+	if fr.cancel != nil {
+		fr.cancel()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	fr.cancel = cancel
+	version := b.Version
+	params := &protocol.FoldingRangeParams{
+		TextDocument: b.ToTextDocumentIdentifier(),
+	}
+	server := v.server
+	v.tomb.Go(func() error {
+		res, err := server.FoldingRange(ctx, params)
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+		if err != nil {
+			v.Logf("foldingRange call failed: %v", err)
+			return nil
+		}
+		v.Schedule(func(govim.Govim) error {
+			select {
+			case <-ctx.Done():
+				return nil
+			default:
+			}
+			fr.cancel = nil
+			// The buffer might have changed (or gone away) in the meantime, in
+			// which case another update will follow
+			if nb, ok := v.buffers[b.Num]; !ok || nb != b || b.Version != version || v.foldingRanges[b.Num] != fr {
+				return nil
+			}
+			fr.version = version
+			fr.ranges = v.classifyFoldingRanges(b, res)
+			return v.renderFoldingRanges(b, fr)
+		})
+		return nil
+	})
+}
+
+// classifyFoldingRanges determines the kind of each of the ranges returned
+// by gopls, adding ranges for composite literals which gopls does not
+// report. gopls only distinguishes imports and comments, so function bodies
+// are identified from the buffer's AST.
+func (v *vimstate) classifyFoldingRanges(b *types.Buffer, ranges []protocol.FoldingRange) []foldingRange {
+	// Ensure we block for the result of any in-flight parse
+	if b.ASTWait != nil {
+		<-b.ASTWait
+	}
+	// funcBodies is the set of offsets at which function bodies start (just
+	// after the opening brace), which is where gopls starts a folding range
+	// for a block
+	funcBodies := make(map[int]bool)
+	var res []foldingRange
+	var tf *token.File
+	if b.AST != nil {
+		tf = b.Fset.File(b.AST.Pos())
+	}
+	if tf != nil {
+		ast.Inspect(b.AST, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.FuncDecl:
+				if n.Body != nil && n.Body.Lbrace.IsValid() {
+					funcBodies[tf.Offset(n.Body.Lbrace)+1] = true
+				}
+			case *ast.FuncLit:
+				if n.Body != nil && n.Body.Lbrace.IsValid() {
+					funcBodies[tf.Offset(n.Body.Lbrace)+1] = true
+				}
+			case *ast.CompositeLit:
+				// Mirror the line folding of gopls: fold from the line of the
+				// opening brace to the end of the last element
+				if !n.Lbrace.IsValid() || !n.Rbrace.IsValid() || len(n.Elts) == 0 {
+					break
+				}
+				start := tf.Line(n.Lbrace)
+				end := tf.Line(n.Elts[len(n.Elts)-1].End())
+				if start == tf.Line(n.Elts[0].Pos()) || end == tf.Line(n.Rbrace) || start == end {
+					break
+				}
+				res = append(res, foldingRange{
+					start: start,
+					end:   end,
+					kind:  config.FoldingRangeKindComposite,
+				})
+			}
+			return true
+		})
+	}
+	for _, r := range ranges {
+		fr := foldingRange{
+			start: int(r.StartLine) + 1,
+			end:   int(r.EndLine) + 1,
+			kind:  config.FoldingRangeKindOther,
+		}
+		switch r.Kind {
+		case string(protocol.Imports):
+			fr.kind = config.FoldingRangeKindImports
+		case string(protocol.Comment):
+			fr.kind = config.FoldingRangeKindComment
+		default:
+			start := protocol.Position{Line: r.StartLine, Character: r.StartCharacter}
+			if p, err := types.PointFromPosition(b, start); err == nil && funcBodies[p.Offset()] {
+				fr.kind = config.FoldingRangeKindFunction
+			}
+		}
+		res = append(res, fr)
+	}
+	sort.SliceStable(res, func(i, j int) bool {
+		if res[i].start != res[j].start {
+			return res[i].start < res[j].start
+		}
+		return res[i].end > res[j].end
+	})
+	return res
+}
+
+// renderFoldingRanges sets the fold level of each line of b, as used by
+// GOVIMFoldExpr(), from those folding ranges in fr whose kind is enabled.
+// Windows showing b that use foldmethod=expr then have their folds updated.
+func (v *vimstate) renderFoldingRanges(b *types.Buffer, fr *bufFoldingRanges) error {
+	kinds := make(map[config.FoldingRangeKind]bool)
+	if v.config.FoldingRangeKinds != nil {
+		for _, k := range *v.config.FoldingRangeKinds {
+			kinds[k] = true
+		}
+	}
+	lineCount := bytes.Count(b.Contents(), []byte("\n"))
+	levels := make([]int, lineCount+1)
+	starts := make([]bool, lineCount+1)
+	for _, r := range fr.ranges {
+		if !kinds[r.kind] || r.start < 1 || r.end > lineCount || r.start >= r.end {
+			continue
+		}
+		starts[r.start] = true
+		for l := r.start; l <= r.end; l++ {
+			levels[l]++
+		}
+	}
+	// Vim wants a string for the start of a fold; we use strings throughout
+	// for consistency
+	exprs := make([]string, lineCount)
+	for l := 1; l <= lineCount; l++ {
+		exprs[l-1] = fmt.Sprint(levels[l])
+		if starts[l] {
+			exprs[l-1] = ">" + exprs[l-1]
+		}
+	}
+	v.ChannelCall("setbufvar", b.Num, foldLevelsVar, exprs)
+	v.refreshFolds(b.Num)
+	return nil
+}
+
+// refreshFolds causes Vim to recompute the folds of all windows showing
+// bufnr that use foldmethod=expr
+func (v *vimstate) refreshFolds(bufnr int) {
+	var wins []int
+	v.Parse(v.ChannelCall("win_findbuf", bufnr), &wins)
+	for _, w := range wins {
+		v.ChannelCall("win_execute", w, "if &l:foldmethod ==# 'expr' | let &l:foldmethod = 'expr' | endif")
+	}
+}
+
+// rerenderFoldingRanges re-renders the folding ranges of all buffers, for
+// example because the enabled kinds have changed.
+func (v *vimstate) rerenderFoldingRanges() error {
+	for bufnr, fr := range v.foldingRanges {
+		b, ok := v.buffers[bufnr]
+		if !ok || fr.version != b.Version {
+			continue
+		}
+		if err := v.renderFoldingRanges(b, fr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeAllFoldingRanges removes the fold levels of all buffers, and
+// forgets all cached folding ranges
+func (v *vimstate) removeAllFoldingRanges() {
+	for bufnr, fr := range v.foldingRanges {
+		if fr.cancel != nil {
+			fr.cancel()
+		}
+		if b, ok := v.buffers[bufnr]; ok && b.Loaded {
+			v.ChannelCall("setbufvar", bufnr, foldLevelsVar, []string{})
+			v.refreshFolds(bufnr)
+		}
+	}
+	v.foldingRanges = make(map[int]*bufFoldingRanges)
+}
@@ -6,46 +6,163 @@ import (
 	"strings"
 
 	"github.com/govim/govim"
+	"github.com/govim/govim/cmd/govim/config"
+	"github.com/govim/govim/cmd/govim/internal/golang_org_x_tools/jsonrpc2"
 	"github.com/govim/govim/cmd/govim/internal/golang_org_x_tools/lsp/protocol"
 	"github.com/govim/govim/cmd/govim/internal/types"
 	"github.com/kr/pretty"
 )
 
 func (v *vimstate) gotoDef(flags govim.CommandFlags, args ...string) error {
+	return v.gotoLocations("definition", flags, args, func(params protocol.TextDocumentPositionParams) ([]protocol.Location, error) {
+		locs, err := v.server.Definition(context.Background(), &protocol.DefinitionParams{
+			TextDocumentPositionParams: params,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to call gopls.Definition: %v\nparams were: %v", err, pretty.Sprint(params))
+		}
+		return locs, nil
+	})
+}
+
+func (v *vimstate) gotoTypeDef(flags govim.CommandFlags, args ...string) error {
+	return v.gotoLocations("type definition", flags, args, func(params protocol.TextDocumentPositionParams) ([]protocol.Location, error) {
+		locs, err := v.server.TypeDefinition(context.Background(), &protocol.TypeDefinitionParams{
+			TextDocumentPositionParams: params,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to call gopls.TypeDefinition: %v\nparams were: %v", err, pretty.Sprint(params))
+		}
+		return locs, nil
+	})
+}
+
+func (v *vimstate) gotoDecl(flags govim.CommandFlags, args ...string) error {
+	return v.gotoLocations("declaration", flags, args, func(params protocol.TextDocumentPositionParams) ([]protocol.Location, error) {
+		locs, err := v.server.Declaration(context.Background(), &protocol.DeclarationParams{
+			TextDocumentPositionParams: params,
+		})
+		if jerr, ok := err.(*jsonrpc2.Error); ok && jerr.Code == jsonrpc2.CodeMethodNotFound {
+			// In Go, the declaration of an identifier is its definition
+			v.Logf("gopls does not support declarations; using definition instead")
+			locs, err = v.server.Definition(context.Background(), &protocol.DefinitionParams{
+				TextDocumentPositionParams: params,
+			})
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to call gopls.Declaration: %v\nparams were: %v", err, pretty.Sprint(params))
+		}
+		return locs, nil
+	})
+}
+
+// locationPopup is the state of a popup menu from which one of several
+// target locations can be picked (see gotoLocations)
+type locationPopup struct {
+	id   int
+	mods govim.CommModList
+	args []string
+	locs []protocol.Location
+
+	// from is the location from which the popup was opened
+	from protocol.Location
+}
+
+// gotoLocations jumps to the location(s) of the identifier under the cursor
+// returned by find. what describes the locations for the purposes of
+// messages. A single location is jumped to directly; multiple locations are
+// presented as per config.Config.GoToMultipleLocations. In either case the
+// current location is pushed onto the jump stack when a jump is made, and
+// args is as per loadLocation.
+func (v *vimstate) gotoLocations(what string, flags govim.CommandFlags, args []string, find func(protocol.TextDocumentPositionParams) ([]protocol.Location, error)) error {
 	cb, pos, err := v.cursorPos()
 	if err != nil {
 		return fmt.Errorf("failed to determine cursor position: %v", err)
 	}
-	params := &protocol.DefinitionParams{
-		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
-			TextDocument: cb.ToTextDocumentIdentifier(),
-			Position:     pos.ToPosition(),
-		},
-	}
-	locs, err := v.server.Definition(context.Background(), params)
+	locs, err := find(protocol.TextDocumentPositionParams{
+		TextDocument: cb.ToTextDocumentIdentifier(),
+		Position:     pos.ToPosition(),
+	})
 	if err != nil {
-		return fmt.Errorf("failed to call gopls.Definition: %v\nparams were: %v", err, pretty.Sprint(params))
+		return err
+	}
+	from := protocol.Location{
+		URI: protocol.DocumentURI(cb.URI()),
+		Range: protocol.Range{
+			Start: pos.ToPosition(),
+			End:   pos.ToPosition(),
+		},
 	}
 
 	switch len(locs) {
 	case 0:
-		v.ChannelEx(`echorerr "No definition exists under cursor"`)
+		v.ChannelExf(`echom "No %v exists under cursor"`, what)
 		return nil
 	case 1:
-	default:
-		return fmt.Errorf("got multiple locations (%v); don't know how to handle this", len(locs))
+		v.pushJumpStack(from)
+		return v.loadLocation(flags.Mods, locs[0], args...)
 	}
 
-	loc := locs[0]
-	v.jumpStack = append(v.jumpStack[:v.jumpStackPos], protocol.Location{
-		URI: protocol.DocumentURI(cb.URI()),
-		Range: protocol.Range{
-			Start: pos.ToPosition(),
-			End:   pos.ToPosition(),
-		},
-	})
+	qf, err := v.locationsToQuickfix(locs, true)
+	if err != nil {
+		return fmt.Errorf("failed to convert locations: %v", err)
+	}
+	title := fmt.Sprintf("%v %vs", len(locs), what)
+	if v.config.GoToMultipleLocations != nil && *v.config.GoToMultipleLocations == config.GoToMultipleLocationsLocationList {
+		// The user chooses where to go from here, so the jump stack is the
+		// only way back
+		v.pushJumpStack(from)
+		v.BatchStart()
+		v.BatchChannelCall("setloclist", 0, qf, "r")
+		v.BatchChannelCall("setloclist", 0, []quickfixEntry{}, "a", map[string]string{"title": title})
+		v.MustBatchEnd()
+		v.ChannelEx("lopen")
+		return nil
+	}
+
+	if v.locationPopup != nil {
+		v.ChannelCall("popup_close", v.locationPopup.id)
+		v.locationPopup = nil
+	}
+	lines := make([]string, len(qf))
+	for i, q := range qf {
+		lines[i] = fmt.Sprintf("%v:%v:%v: %v", q.Filename, q.Lnum, q.Col, strings.TrimSpace(q.Text))
+	}
+	opts := map[string]interface{}{
+		"title":      " " + title + " ",
+		"padding":    []int{0, 1, 0, 1},
+		"border":     []int{},
+		"cursorline": 1,
+		"wrap":       false,
+		"mapping":    0,
+		"drag":       1,
+		"filter":     "popup_filter_menu",
+		"callback":   "GOVIM" + config.FunctionPopupSelection,
+	}
+	v.locationPopup = &locationPopup{
+		id:   v.ParseInt(v.ChannelCall("popup_create", lines, opts)),
+		mods: flags.Mods,
+		args: args,
+		locs: locs,
+		from: from,
+	}
+	return nil
+}
+
+// locationSelected handles the selection of the 1-indexed selection from lp
+func (v *vimstate) locationSelected(lp *locationPopup, selection int) error {
+	if selection < 1 || selection > len(lp.locs) { // 0 = popup_close() called, -1 = ESC closed popup
+		return nil
+	}
+	v.pushJumpStack(lp.from)
+	return v.loadLocation(lp.mods, lp.locs[selection-1], lp.args...)
+}
+
+// pushJumpStack pushes loc onto the jump stack, truncating the stack at the
+// current position
+func (v *vimstate) pushJumpStack(loc protocol.Location) {
+	v.jumpStack = append(v.jumpStack[:v.jumpStackPos], loc)
 	v.jumpStackPos++
-	return v.loadLocation(flags.Mods, loc, args...)
 }
 
 func (v *vimstate) gotoPrevDef(flags govim.CommandFlags, args ...string) error {
@@ -62,7 +179,8 @@ func (v *vimstate) gotoPrevDef(flags govim.CommandFlags, args ...string) error {
 	return v.loadLocation(flags.Mods, loc, args...)
 }
 
-// args is expected to be the command args for either gotodef or gotoprevdef
+// args is expected to be the command args for one of the go to commands, e.g.
+// gotodef or gotoprevdef
 func (v *vimstate) loadLocation(mods govim.CommModList, loc protocol.Location, args ...string) error {
 	// We expect at most one argument that is the a string value appropriate
 	// for &switchbuf. This will need parsing if supplied
@@ -3,6 +3,7 @@ package main
 import (
 	"bufio"
 	"context"
+	"encoding/json"
 	"fmt"
 	"math"
 	"os"
@@ -16,9 +17,29 @@ import (
 	"github.com/govim/govim/cmd/govim/internal/golang_org_x_tools/lsp/protocol"
 	"github.com/govim/govim/cmd/govim/internal/golang_org_x_tools/span"
 	"github.com/govim/govim/cmd/govim/internal/util"
+	"github.com/kr/pretty"
 )
 
+// startGopls starts gopls for the first time, with the current working
+// directory as the sole workspace folder
 func (g *govimplugin) startGopls() error {
+	root, err := g.vimstate.newWorkspaceFolder(g.vimstate.workingDirectory)
+	if err != nil {
+		return err
+	}
+	g.workspaceFolders = []workspaceFolder{root}
+	if err := g.launchGopls(); err != nil {
+		return err
+	}
+	g.workspaceLock.Lock()
+	defer g.workspaceLock.Unlock()
+	return g.watchModule(root)
+}
+
+// launchGopls starts a gopls process, connects to it and initializes it with
+// the current workspace folders. It is used both to start gopls for the first
+// time and to restart it.
+func (g *govimplugin) launchGopls() error {
 	logfile, err := g.createLogFile("gopls")
 	if err != nil {
 		return err
@@ -83,77 +104,130 @@ func (g *govimplugin) startGopls() error {
 	if err != nil {
 		return fmt.Errorf("failed to create stdin pipe for gopls: %v", err)
 	}
-	g.goplsStdin = stdin
 	if err := gopls.Start(); err != nil {
 		return fmt.Errorf("failed to start gopls: %v", err)
 	}
+	// stop is closed when this gopls is deliberately stopped, i.e. restarted,
+	// and exited is closed once the process has exited
+	stop := make(chan struct{})
+	exited := make(chan struct{})
 	g.tomb.Go(func() (err error) {
 		if err = gopls.Wait(); err != nil {
 			err = fmt.Errorf("got error running gopls: %v", err)
 		}
+		close(exited)
 		select {
 		case <-g.inShutdown:
 			return nil
+		case <-stop:
+			return nil
 		default:
-			if err != nil {
-				g.errCh <- err
-			}
-			return
+			g.goplsExited(err)
+			return nil
 		}
 	})
 
 	stream := jsonrpc2.NewHeaderStream(stdout, stdin)
 	ctxt, cancel := context.WithCancel(context.Background())
 	conn := jsonrpc2.NewConn(stream)
-	server := protocol.ServerDispatcher(conn)
-	handler := protocol.ClientHandler(g, jsonrpc2.MethodNotFound)
+	server := loggingGoplsServer{
+		u: protocol.ServerDispatcher(conn),
+		g: g,
+	}
+	handler := g.applyEditHandler(protocol.ClientHandler(g, jsonrpc2.MethodNotFound))
 	handler = protocol.Handlers(handler)
 	ctxt = protocol.WithClient(ctxt, g)
 
 	g.tomb.Go(func() error {
-		return conn.Run(ctxt, handler)
+		err := conn.Run(ctxt, handler)
+		select {
+		case <-stop:
+			return nil
+		default:
+		}
+		if g.goplsCrashPolicy() == config.GoplsCrashPolicyRestart {
+			// Whether or not gopls has exited, it is of no further use. The
+			// process will either exit (and be restarted) of its own accord, or
+			// can be restarted via CommandGoplsRestart
+			g.Logf("gopls connection closed: %v", err)
+			return nil
+		}
+		return err
 	})
 
-	g.gopls = gopls.Process
-	g.goplsConn = conn
-	g.goplsCancel = cancel
-	g.server = loggingGoplsServer{
-		u: server,
-		g: g,
+	// abort stops this gopls if it cannot be initialised. Until we return
+	// successfully, none of the state of g refers to this gopls.
+	abort := func(err error) error {
+		close(stop)
+		stdin.Close()
+		gopls.Process.Kill()
+		cancel()
+		return err
 	}
 
 	initParams := &protocol.ParamInitialize{}
 	initParams.RootURI = protocol.DocumentURI(span.URIFromPath(g.vimstate.workingDirectory))
+	g.workspaceLock.Lock()
+	for _, w := range g.workspaceFolders {
+		initParams.WorkspaceFolders = append(initParams.WorkspaceFolders, w.protocol())
+	}
+	g.workspaceLock.Unlock()
+	initParams.Capabilities.Workspace.WorkspaceFolders = true
 	initParams.Capabilities.TextDocument.Hover = protocol.HoverClientCapabilities{
-		ContentFormat: []protocol.MarkupKind{protocol.PlainText},
+		ContentFormat: []protocol.MarkupKind{protocol.Markdown, protocol.PlainText},
+	}
+	initParams.Capabilities.TextDocument.DocumentSymbol.HierarchicalDocumentSymbolSupport = true
+	initParams.Capabilities.TextDocument.Rename.PrepareSupport = true
+	initParams.Capabilities.TextDocument.FoldingRange.LineFoldingOnly = true
+	if c := g.vimstate.config.CompletionSnippets; c != nil && *c {
+		initParams.Capabilities.TextDocument.Completion.CompletionItem.SnippetSupport = true
 	}
 	initParams.Capabilities.Workspace.Configuration = true
+	initParams.Capabilities.Window.WorkDoneProgress = true
+	initParams.Capabilities.Workspace.ApplyEdit = true
+	initParams.Capabilities.Workspace.WorkspaceEdit = protocol.WorkspaceEditClientCapabilities{
+		DocumentChanges:    true,
+		ResourceOperations: []protocol.ResourceOperationKind{protocol.Create, protocol.Rename, protocol.Delete},
+		FailureHandling:    protocol.Abort,
+	}
 	// TODO: actually handle these registrations dynamically, if we ever want to
 	// target language servers other than gopls.
 	initParams.Capabilities.Workspace.DidChangeConfiguration.DynamicRegistration = true
 	initParams.Capabilities.Workspace.DidChangeWatchedFiles.DynamicRegistration = true
 
-	if _, err := g.server.Initialize(context.Background(), initParams); err != nil {
-		return fmt.Errorf("failed to initialise gopls: %v", err)
-	}
-
-	if err := g.server.Initialized(context.Background(), &protocol.InitializedParams{}); err != nil {
-		return fmt.Errorf("failed to call gopls.Initialized: %v", err)
+	initRes, err := g.initializeGopls(conn, initParams)
+	if err != nil {
+		return abort(fmt.Errorf("failed to initialise gopls: %v", err))
 	}
-
-	gomodpath, err := goModPath(g.vimstate.workingDirectory)
+	semTokCaps, err := parseSemanticTokensCapabilities(initRes)
 	if err != nil {
-		return fmt.Errorf("failed to derive go.mod path: %v", err)
+		return abort(fmt.Errorf("failed to parse gopls semantic tokens capabilities: %v", err))
 	}
 
-	if gomodpath != "" {
-		// i.e. we are in a module
-		mw, err := newModWatcher(g, gomodpath)
-		if err != nil {
-			return fmt.Errorf("failed to create modWatcher for %v: %v", gomodpath, err)
-		}
-		g.modWatcher = mw
+	if err := server.Initialized(context.Background(), &protocol.InitializedParams{}); err != nil {
+		return abort(fmt.Errorf("failed to call gopls.Initialized: %v", err))
 	}
 
+	g.gopls = gopls.Process
+	g.goplsStdin = stdin
+	g.goplsStop = stop
+	g.goplsDone = exited
+	g.goplsConn = conn
+	g.goplsCancel = cancel
+	g.server = server
+	g.semanticTokensCaps = semTokCaps
+
 	return nil
 }
+
+// initializeGopls calls gopls' initialize method, returning the raw result.
+// The protocol package does not (yet) model all of the server capabilities
+// that we use, e.g. the semantic tokens legend, so these must be decoded from
+// the raw result.
+func (g *govimplugin) initializeGopls(conn *jsonrpc2.Conn, params *protocol.ParamInitialize) (json.RawMessage, error) {
+	g.Logf("gopls.Initialize() call; params:\n%v", pretty.Sprint(params))
+	var res json.RawMessage
+	err := conn.Call(context.Background(), "initialize", params, &res)
+	g.Logf("gopls.Initialize() return; err: %v; res:\n%s", err, res)
+	return res, err
+}
@@ -2,7 +2,6 @@ package main
 
 import (
 	"context"
-	"fmt"
 	"os"
 	"reflect"
 	"strings"
@@ -26,6 +25,8 @@ const (
 	goplsTempModfile          = "tempModfile"
 	goplsVerboseOutput        = "verboseOutput"
 	goplsEnv                  = "env"
+	goplsUsePlaceholders      = "usePlaceholders"
+	goplsSemanticTokens       = "semanticTokens"
 )
 
 var _ protocol.Client = (*govimplugin)(nil)
@@ -61,11 +62,6 @@ func (g *govimplugin) ShowMessage(ctxt context.Context, params *protocol.ShowMes
 	return nil
 }
 
-func (g *govimplugin) ShowMessageRequest(context.Context, *protocol.ShowMessageRequestParams) (*protocol.MessageActionItem, error) {
-	defer absorbShutdownErr()
-	panic("ShowMessageRequest not implemented yet")
-}
-
 func (g *govimplugin) LogMessage(ctxt context.Context, params *protocol.LogMessageParams) error {
 	defer absorbShutdownErr()
 	g.logGoplsClientf("LogMessage callback: %v", pretty.Sprint(params))
@@ -88,11 +84,6 @@ func (g *govimplugin) UnregisterCapability(context.Context, *protocol.Unregistra
 	panic("UnregisterCapability not implemented yet")
 }
 
-func (g *govimplugin) WorkspaceFolders(context.Context) ([]protocol.WorkspaceFolder, error) {
-	defer absorbShutdownErr()
-	panic("WorkspaceFolders not implemented yet")
-}
-
 func (g *govimplugin) Configuration(ctxt context.Context, params *protocol.ParamConfiguration) ([]interface{}, error) {
 	defer absorbShutdownErr()
 
@@ -102,13 +93,9 @@ func (g *govimplugin) Configuration(ctxt context.Context, params *protocol.Param
 	conf := g.vimstate.config
 	defer g.vimstate.configLock.Unlock()
 
-	// gopls now sends params.Items for each of the configured
-	// workspaces. For now, we assume that the first item will be
-	// for the section "gopls" and only configure that. We will
-	// configure further workspaces when we add support for them.
-	if len(params.Items) == 0 || params.Items[0].Section != "gopls" {
-		return nil, fmt.Errorf("govim gopls client: expected at least one item, with the first section \"gopls\"")
-	}
+	// gopls sends an item for each workspace folder (identified by
+	// ScopeURI). Every folder shares the same "gopls" configuration; we
+	// have no configuration for any other section.
 	res := make([]interface{}, len(params.Items))
 	goplsConfig := make(map[string]interface{})
 	goplsConfig[goplsConfigHoverKind] = "FullDocumentation"
@@ -127,6 +114,9 @@ func (g *govimplugin) Configuration(ctxt context.Context, params *protocol.Param
 	if conf.GoImportsLocalPrefix != nil {
 		goplsConfig[goplsGoImportsLocalPrefix] = *conf.GoImportsLocalPrefix
 	}
+	if conf.CompletionSnippets != nil {
+		goplsConfig[goplsUsePlaceholders] = *conf.CompletionSnippets
+	}
 	if conf.CompletionBudget != nil {
 		goplsConfig[goplsCompletionBudget] = *conf.CompletionBudget
 	}
@@ -141,15 +131,29 @@ func (g *govimplugin) Configuration(ctxt context.Context, params *protocol.Param
 		// Vim creates a new map.
 		goplsConfig[goplsEnv] = *conf.GoplsEnv
 	}
-	res[0] = goplsConfig
+	if conf.SemanticHighlighting != nil && *conf.SemanticHighlighting {
+		// Only set when enabled, because not all versions of gopls know of
+		// this option
+		goplsConfig[goplsSemanticTokens] = true
+	}
+	for i, item := range params.Items {
+		if item.Section == "gopls" {
+			res[i] = goplsConfig
+		}
+	}
 
 	g.logGoplsClientf("Configuration response: %v", pretty.Sprint(res))
 	return res, nil
 }
 
-func (g *govimplugin) ApplyEdit(context.Context, *protocol.ApplyWorkspaceEditParams) (*protocol.ApplyWorkspaceEditResponse, error) {
-	defer absorbShutdownErr()
-	panic("ApplyEdit not implemented yet")
+// ApplyEdit is only called for workspace/applyEdit requests that are not
+// intercepted by applyEditHandler, which is where such requests are ordinarily
+// handled.
+func (g *govimplugin) ApplyEdit(ctxt context.Context, params *protocol.ApplyWorkspaceEditParams) (*protocol.ApplyWorkspaceEditResponse, error) {
+	return g.applyEdit(&applyWorkspaceEditParams{
+		Label: params.Label,
+		Edit:  toWorkspaceEdit(params.Edit),
+	})
 }
 
 func (g *govimplugin) Event(context.Context, *interface{}) error {
@@ -186,15 +190,6 @@ func (g *govimplugin) PublishDiagnostics(ctxt context.Context, params *protocol.
 	return nil
 }
 
-func (g *govimplugin) Progress(ctxt context.Context, params *protocol.ProgressParams) error {
-	defer absorbShutdownErr()
-	panic("Progress not implemented yet")
-}
-func (g *govimplugin) WorkDoneProgressCreate(ctxt context.Context, params *protocol.WorkDoneProgressCreateParams) error {
-	defer absorbShutdownErr()
-	panic("WorkDoneProgressCreate not implemented yet")
-}
-
 func absorbShutdownErr() {
 	if r := recover(); r != nil && r != govim.ErrShuttingDown {
 		panic(r)
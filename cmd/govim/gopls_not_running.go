@@ -0,0 +1,217 @@
+package main
+
+import (
+	"context"
+	"errors"
+
+	"github.com/govim/govim/cmd/govim/internal/golang_org_x_tools/lsp/protocol"
+)
+
+// errGoplsNotRunning is the error returned by notRunningServer
+var errGoplsNotRunning = errors.New("gopls is not running; try GOVIMGoplsRestart")
+
+// notRunningServer is the server used in place of gopls when there is no
+// current gopls, i.e. when gopls could not be restarted. Every method returns
+// errGoplsNotRunning, such that commands and the like fail with a clear error,
+// rather than each caller having to check whether gopls is running.
+type notRunningServer struct{}
+
+func (notRunningServer) DidChangeWorkspaceFolders(context.Context, *protocol.DidChangeWorkspaceFoldersParams) error {
+	return errGoplsNotRunning
+}
+
+func (notRunningServer) WorkDoneProgressCancel(context.Context, *protocol.WorkDoneProgressCancelParams) error {
+	return errGoplsNotRunning
+}
+
+func (notRunningServer) Initialized(context.Context, *protocol.InitializedParams) error {
+	return errGoplsNotRunning
+}
+
+func (notRunningServer) Exit(context.Context) error {
+	return errGoplsNotRunning
+}
+
+func (notRunningServer) DidChangeConfiguration(context.Context, *protocol.DidChangeConfigurationParams) error {
+	return errGoplsNotRunning
+}
+
+func (notRunningServer) DidOpen(context.Context, *protocol.DidOpenTextDocumentParams) error {
+	return errGoplsNotRunning
+}
+
+func (notRunningServer) DidChange(context.Context, *protocol.DidChangeTextDocumentParams) error {
+	return errGoplsNotRunning
+}
+
+func (notRunningServer) DidClose(context.Context, *protocol.DidCloseTextDocumentParams) error {
+	return errGoplsNotRunning
+}
+
+func (notRunningServer) DidSave(context.Context, *protocol.DidSaveTextDocumentParams) error {
+	return errGoplsNotRunning
+}
+
+func (notRunningServer) WillSave(context.Context, *protocol.WillSaveTextDocumentParams) error {
+	return errGoplsNotRunning
+}
+
+func (notRunningServer) DidChangeWatchedFiles(context.Context, *protocol.DidChangeWatchedFilesParams) error {
+	return errGoplsNotRunning
+}
+
+func (notRunningServer) SetTraceNotification(context.Context, *protocol.SetTraceParams) error {
+	return errGoplsNotRunning
+}
+
+func (notRunningServer) LogTraceNotification(context.Context, *protocol.LogTraceParams) error {
+	return errGoplsNotRunning
+}
+
+func (notRunningServer) Implementation(context.Context, *protocol.ImplementationParams) (protocol.Definition, error) {
+	return nil, errGoplsNotRunning
+}
+
+func (notRunningServer) TypeDefinition(context.Context, *protocol.TypeDefinitionParams) (protocol.Definition, error) {
+	return nil, errGoplsNotRunning
+}
+
+func (notRunningServer) DocumentColor(context.Context, *protocol.DocumentColorParams) ([]protocol.ColorInformation, error) {
+	return nil, errGoplsNotRunning
+}
+
+func (notRunningServer) ColorPresentation(context.Context, *protocol.ColorPresentationParams) ([]protocol.ColorPresentation, error) {
+	return nil, errGoplsNotRunning
+}
+
+func (notRunningServer) FoldingRange(context.Context, *protocol.FoldingRangeParams) ([]protocol.FoldingRange, error) {
+	return nil, errGoplsNotRunning
+}
+
+func (notRunningServer) Declaration(context.Context, *protocol.DeclarationParams) (protocol.Declaration, error) {
+	return nil, errGoplsNotRunning
+}
+
+func (notRunningServer) SelectionRange(context.Context, *protocol.SelectionRangeParams) ([]protocol.SelectionRange, error) {
+	return nil, errGoplsNotRunning
+}
+
+func (notRunningServer) Initialize(context.Context, *protocol.ParamInitialize) (*protocol.InitializeResult, error) {
+	return nil, errGoplsNotRunning
+}
+
+func (notRunningServer) Shutdown(context.Context) error {
+	return errGoplsNotRunning
+}
+
+func (notRunningServer) WillSaveWaitUntil(context.Context, *protocol.WillSaveTextDocumentParams) ([]protocol.TextEdit, error) {
+	return nil, errGoplsNotRunning
+}
+
+func (notRunningServer) Completion(context.Context, *protocol.CompletionParams) (*protocol.CompletionList, error) {
+	return nil, errGoplsNotRunning
+}
+
+func (notRunningServer) Resolve(context.Context, *protocol.CompletionItem) (*protocol.CompletionItem, error) {
+	return nil, errGoplsNotRunning
+}
+
+func (notRunningServer) Hover(context.Context, *protocol.HoverParams) (*protocol.Hover, error) {
+	return nil, errGoplsNotRunning
+}
+
+func (notRunningServer) SignatureHelp(context.Context, *protocol.SignatureHelpParams) (*protocol.SignatureHelp, error) {
+	return nil, errGoplsNotRunning
+}
+
+func (notRunningServer) Definition(context.Context, *protocol.DefinitionParams) (protocol.Definition, error) {
+	return nil, errGoplsNotRunning
+}
+
+func (notRunningServer) References(context.Context, *protocol.ReferenceParams) ([]protocol.Location, error) {
+	return nil, errGoplsNotRunning
+}
+
+func (notRunningServer) DocumentHighlight(context.Context, *protocol.DocumentHighlightParams) ([]protocol.DocumentHighlight, error) {
+	return nil, errGoplsNotRunning
+}
+
+func (notRunningServer) DocumentSymbol(context.Context, *protocol.DocumentSymbolParams) ([]interface{}, error) {
+	return nil, errGoplsNotRunning
+}
+
+func (notRunningServer) CodeAction(context.Context, *protocol.CodeActionParams) ([]protocol.CodeAction, error) {
+	return nil, errGoplsNotRunning
+}
+
+func (notRunningServer) Symbol(context.Context, *protocol.WorkspaceSymbolParams) ([]protocol.SymbolInformation, error) {
+	return nil, errGoplsNotRunning
+}
+
+func (notRunningServer) CodeLens(context.Context, *protocol.CodeLensParams) ([]protocol.CodeLens, error) {
+	return nil, errGoplsNotRunning
+}
+
+func (notRunningServer) ResolveCodeLens(context.Context, *protocol.CodeLens) (*protocol.CodeLens, error) {
+	return nil, errGoplsNotRunning
+}
+
+func (notRunningServer) DocumentLink(context.Context, *protocol.DocumentLinkParams) ([]protocol.DocumentLink, error) {
+	return nil, errGoplsNotRunning
+}
+
+func (notRunningServer) ResolveDocumentLink(context.Context, *protocol.DocumentLink) (*protocol.DocumentLink, error) {
+	return nil, errGoplsNotRunning
+}
+
+func (notRunningServer) Formatting(context.Context, *protocol.DocumentFormattingParams) ([]protocol.TextEdit, error) {
+	return nil, errGoplsNotRunning
+}
+
+func (notRunningServer) RangeFormatting(context.Context, *protocol.DocumentRangeFormattingParams) ([]protocol.TextEdit, error) {
+	return nil, errGoplsNotRunning
+}
+
+func (notRunningServer) OnTypeFormatting(context.Context, *protocol.DocumentOnTypeFormattingParams) ([]protocol.TextEdit, error) {
+	return nil, errGoplsNotRunning
+}
+
+func (notRunningServer) Rename(context.Context, *protocol.RenameParams) (*protocol.WorkspaceEdit, error) {
+	return nil, errGoplsNotRunning
+}
+
+func (notRunningServer) PrepareRename(context.Context, *protocol.PrepareRenameParams) (*protocol.Range, error) {
+	return nil, errGoplsNotRunning
+}
+
+func (notRunningServer) ExecuteCommand(context.Context, *protocol.ExecuteCommandParams) (interface{}, error) {
+	return nil, errGoplsNotRunning
+}
+
+func (notRunningServer) PrepareCallHierarchy(context.Context, *protocol.CallHierarchyPrepareParams) ([]protocol.CallHierarchyItem, error) {
+	return nil, errGoplsNotRunning
+}
+
+func (notRunningServer) IncomingCalls(context.Context, *protocol.CallHierarchyIncomingCallsParams) ([]protocol.CallHierarchyIncomingCall, error) {
+	return nil, errGoplsNotRunning
+}
+
+func (notRunningServer) OutgoingCalls(context.Context, *protocol.CallHierarchyOutgoingCallsParams) ([]protocol.CallHierarchyOutgoingCall, error) {
+	return nil, errGoplsNotRunning
+}
+
+func (notRunningServer) SemanticTokens(context.Context, *protocol.SemanticTokensParams) (*protocol.SemanticTokens, error) {
+	return nil, errGoplsNotRunning
+}
+
+func (notRunningServer) SemanticTokensEdits(context.Context, *protocol.SemanticTokensEditsParams) (interface{}, error) {
+	return nil, errGoplsNotRunning
+}
+
+func (notRunningServer) SemanticTokensRange(context.Context, *protocol.SemanticTokensRangeParams) (*protocol.SemanticTokens, error) {
+	return nil, errGoplsNotRunning
+}
+
+func (notRunningServer) NonstandardRequest(context.Context, string, interface{}) (interface{}, error) {
+	return nil, errGoplsNotRunning
+}
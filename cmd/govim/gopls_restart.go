@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/govim/govim"
+	"github.com/govim/govim/cmd/govim/config"
+	"github.com/govim/govim/cmd/govim/internal/golang_org_x_tools/lsp/protocol"
+)
+
+const (
+	// goplsShutdownTimeout is how long we wait for a gopls that is being
+	// restarted to shutdown and exit, before killing it
+	goplsShutdownTimeout = 5 * time.Second
+
+	// goplsMaxCrashRestarts is the maximum number of times gopls is
+	// automatically restarted within goplsCrashWindow, under
+	// config.GoplsCrashPolicyRestart. Any more and we give up.
+	goplsMaxCrashRestarts = 3
+	goplsCrashWindow      = time.Minute
+)
+
+// goplsCrashPolicy returns the current config.GoplsCrashPolicy. It is safe to
+// call from any goroutine.
+func (g *govimplugin) goplsCrashPolicy() config.GoplsCrashPolicy {
+	g.vimstate.configLock.Lock()
+	defer g.vimstate.configLock.Unlock()
+	if p := g.vimstate.config.GoplsCrashPolicy; p != nil {
+		return *p
+	}
+	return config.GoplsCrashPolicyExit
+}
+
+// goplsExited is called when the gopls process exits for any reason other
+// than govim shutting down or restarting it. err is the error (if any) from
+// the process.
+func (g *govimplugin) goplsExited(err error) {
+	if g.goplsCrashPolicy() != config.GoplsCrashPolicyRestart {
+		if err != nil {
+			g.goplsFatal(err)
+		}
+		return
+	}
+	if err == nil {
+		err = fmt.Errorf("gopls exited unexpectedly")
+	}
+	// goplsCrashes is only used here, and there is only ever one gopls
+	// process (and hence one call to goplsExited) at a time
+	now := time.Now()
+	var recent []time.Time
+	for _, t := range g.goplsCrashes {
+		if now.Sub(t) < goplsCrashWindow {
+			recent = append(recent, t)
+		}
+	}
+	if len(recent) >= goplsMaxCrashRestarts {
+		g.goplsFatal(fmt.Errorf("gopls exited %v times within %v; giving up: %v", len(recent)+1, goplsCrashWindow, err))
+		return
+	}
+	g.goplsCrashes = append(recent, now)
+	g.Logf("%v; restarting gopls", err)
+	g.Schedule(func(govim.Govim) error {
+		v := g.vimstate
+		v.ChannelExf("echohl WarningMsg | echom %q | echohl None", "gopls exited unexpectedly; restarting")
+		if err := v.restartGopls(); err != nil {
+			g.goplsFatal(err)
+		}
+		return nil
+	})
+}
+
+// goplsFatal reports err to govim as a fatal error, causing govim to exit.
+// govim only receives the first error sent on errCh, hence any later errors
+// are only logged; sending them would block forever. goplsFatal is called
+// from the Vim thread as well as other goroutines.
+func (g *govimplugin) goplsFatal(err error) {
+	g.goplsFatalLock.Lock()
+	logOnly := g.goplsFatalLogOnly
+	g.goplsFatalLock.Unlock()
+	if logOnly {
+		g.Logf("fatal gopls error: %v", err)
+		return
+	}
+	reported := false
+	g.goplsFatalOnce.Do(func() {
+		reported = true
+		// errCh is closed once govim is dying
+		select {
+		case <-g.tomb.Dying():
+			return
+		default:
+		}
+		select {
+		case g.errCh <- err:
+		case <-g.tomb.Dying():
+		}
+	})
+	if !reported {
+		g.Logf("not reporting gopls error; a fatal error has already been reported: %v", err)
+	}
+}
+
+// goplsRestart is the implementation of config.CommandGoplsRestart
+func (v *vimstate) goplsRestart(flags govim.CommandFlags, args ...string) error {
+	return v.restartGopls()
+}
+
+// restartGopls stops the current gopls, if any, starts a new gopls and brings
+// it up to date with the current state of Vim: the new gopls is told about
+// each buffer, at its current version. Diagnostics are left intact until the
+// new gopls publishes diagnostics of its own. If the new gopls fails to start,
+// there is no current gopls until restartGopls is called again: in the
+// meantime requests fail with errGoplsNotRunning.
+func (v *vimstate) restartGopls() error {
+	v.Logf("restarting gopls")
+	if v.goplsStop != nil {
+		v.stopGopls()
+	}
+
+	// Work done progress is specific to a gopls instance
+	v.progressLock.Lock()
+	v.progress = make(map[string]*workDoneProgress)
+	v.progressLock.Unlock()
+
+	if err := v.launchGopls(); err != nil {
+		return fmt.Errorf("failed to restart gopls: %v", err)
+	}
+
+	var bufs []int
+	for n, b := range v.buffers {
+		if b.Version > 0 {
+			bufs = append(bufs, n)
+		}
+	}
+	sort.Ints(bufs)
+	for _, n := range bufs {
+		b := v.buffers[n]
+		params := &protocol.DidOpenTextDocumentParams{
+			TextDocument: protocol.TextDocumentItem{
+				LanguageID: "go",
+				URI:        protocol.DocumentURI(b.URI()),
+				Version:    float64(b.Version),
+				Text:       string(b.Contents()),
+			},
+		}
+		if err := v.server.DidOpen(context.Background(), params); err != nil {
+			return fmt.Errorf("failed to call gopls.DidOpen for %v: %v", b.Name, err)
+		}
+	}
+
+	// Anything cached from the old gopls needs to be refetched
+	for _, cl := range v.codeLenses {
+		cl.version = -1
+	}
+	v.updateCodeLenses()
+	v.completionResolveUnsupported = false
+	v.removeAllSemanticHighlighting()
+	v.semanticTokensUnsupported = false
+	if err := v.updateSemanticHighlighting(); err != nil {
+		return err
+	}
+	for bufnr, fr := range v.foldingRanges {
+		fr.version = -1
+		if b, ok := v.buffers[bufnr]; ok {
+			v.updateFoldingRanges(b)
+		}
+	}
+	if err := v.progressChanged(); err != nil {
+		return err
+	}
+	v.Logf("gopls restarted")
+	return nil
+}
+
+// stopGopls stops the current gopls in the background; if it is wedged we do
+// not want to block Vim. The state of v that refers to the current gopls is
+// cleared first, such that if a new gopls cannot be started there is no
+// current gopls, rather than one that has been stopped.
+func (v *vimstate) stopGopls() {
+	proc, server, stdin, cancel, stop, done := v.gopls, v.server, v.goplsStdin, v.goplsCancel, v.goplsStop, v.goplsDone
+	v.gopls, v.goplsStdin, v.goplsConn, v.goplsCancel, v.goplsStop, v.goplsDone = nil, nil, nil, nil, nil, nil
+	v.server = notRunningServer{}
+	v.semanticTokensCaps = nil
+	close(stop)
+
+	v.tomb.Go(func() error {
+		ctx, cancelShutdown := context.WithTimeout(context.Background(), goplsShutdownTimeout)
+		defer cancelShutdown()
+		if err := server.Shutdown(ctx); err != nil {
+			v.Logf("failed to shutdown old gopls: %v", err)
+		} else if err := server.Exit(ctx); err != nil {
+			v.Logf("failed to exit old gopls: %v", err)
+		}
+		stdin.Close()
+		select {
+		case <-done:
+		case <-time.After(goplsShutdownTimeout):
+			v.Logf("old gopls did not exit; killing it")
+			proc.Kill()
+		}
+		cancel()
+		return nil
+	})
+}
@@ -53,6 +53,12 @@ func (v *vimstate) textpropDefine() error {
 			Combine:   true, // Combine with syntax highlight
 			Priority:  types.SeverityPriority[s],
 		})
+
+		hi = types.SeverityInlineHighlight[s]
+		v.BatchChannelCall("prop_type_add", hi, propDict{
+			Highlight: string(hi),
+			Priority:  types.SeverityPriority[s],
+		})
 	}
 
 	v.BatchChannelCall("prop_type_add", config.HighlightHoverDiagSrc, propDict{
@@ -61,12 +67,54 @@ func (v *vimstate) textpropDefine() error {
 		Priority:  types.SeverityPriority[types.SeverityErr] + 1,
 	})
 
+	for _, hi := range hoverMarkdownHighlights {
+		v.BatchChannelCall("prop_type_add", hi, propDict{
+			Highlight: string(hi),
+			Combine:   true,
+		})
+	}
+
 	v.BatchChannelCall("prop_type_add", config.HighlightReferences, propDict{
 		Highlight: string(config.HighlightReferences),
 		Combine:   true,
 		Priority:  types.SeverityPriority[types.SeverityErr] + 1,
 	})
 
+	v.BatchChannelCall("prop_type_add", config.HighlightSignatureActiveParameter, propDict{
+		Highlight: string(config.HighlightSignatureActiveParameter),
+		Combine:   true,
+	})
+
+	v.BatchChannelCall("prop_type_add", config.HighlightCodeLens, propDict{
+		Highlight: string(config.HighlightCodeLens),
+		Combine:   true,
+	})
+
+	// Text typed at either end of a placeholder extends the placeholder
+	v.BatchChannelCall("prop_type_add", config.HighlightSnippetPlaceholder, propDict{
+		Highlight: string(config.HighlightSnippetPlaceholder),
+		Combine:   true,
+		StartIncl: true,
+		EndIncl:   true,
+	})
+
+	// Semantic token types are given a lower priority than their modifiers,
+	// so that the latter take precedence when combined
+	for _, hi := range semanticTokenTypeHighlights {
+		v.BatchChannelCall("prop_type_add", hi, propDict{
+			Highlight: string(hi),
+			Combine:   true,
+			Priority:  -2,
+		})
+	}
+	for _, hi := range semanticTokenModifierHighlights {
+		v.BatchChannelCall("prop_type_add", hi, propDict{
+			Highlight: string(hi),
+			Combine:   true,
+			Priority:  -1,
+		})
+	}
+
 	res := v.MustBatchEnd()
 	for i := range res {
 		if v.ParseInt(res[i]) != 0 {
@@ -86,7 +134,7 @@ func (v *vimstate) redefineHighlights(force bool) error {
 	if !force && !work {
 		return nil
 	}
-	diags := *diagsRef
+	diags := v.filterDiagnostics(*diagsRef, v.config.DiagnosticsHighlightSeverity)
 
 	v.removeTextProps(types.DiagnosticTextPropID)
 
@@ -191,16 +239,17 @@ func (v *vimstate) updateReferenceHighlight(force bool, cursorPos *cursorPositio
 	v.cancelDocHighlight = cancel
 	v.cancelDocHighlightLock.Unlock()
 
+	server := v.server
 	v.tomb.Go(func() error {
-		v.redefineReferenceHighlight(ctx, b, pos)
+		v.redefineReferenceHighlight(ctx, server, b, pos)
 		return nil
 	})
 
 	return nil
 }
 
-func (g *govimplugin) redefineReferenceHighlight(ctx context.Context, b *types.Buffer, cursorPos types.Point) {
-	res, err := g.server.DocumentHighlight(ctx,
+func (g *govimplugin) redefineReferenceHighlight(ctx context.Context, server protocol.Server, b *types.Buffer, cursorPos types.Point) {
+	res, err := server.DocumentHighlight(ctx,
 		&protocol.DocumentHighlightParams{
 			TextDocumentPositionParams: protocol.TextDocumentPositionParams{
 				TextDocument: protocol.TextDocumentIdentifier{
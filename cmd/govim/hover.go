@@ -4,8 +4,10 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strconv"
 	"strings"
 
+	"github.com/govim/govim"
 	"github.com/govim/govim/cmd/govim/config"
 	"github.com/govim/govim/cmd/govim/internal/golang_org_x_tools/lsp/protocol"
 	"github.com/govim/govim/cmd/govim/internal/types"
@@ -27,7 +29,9 @@ func (v *vimstate) hover(args ...json.RawMessage) (interface{}, error) {
 	return v.showHover(posExpr, opts, v.config.ExperimentalCursorTriggeredHoverPopupOptions)
 }
 
-func (v *vimstate) hoverMsgAt(pos types.Point, tdi protocol.TextDocumentIdentifier) (string, error) {
+// hoverMsgAt returns the hover contents at pos, which are either plain text
+// or Markdown
+func (v *vimstate) hoverMsgAt(pos types.Point, tdi protocol.TextDocumentIdentifier) (protocol.MarkupContent, error) {
 	params := &protocol.HoverParams{
 		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
 			TextDocument: tdi,
@@ -36,12 +40,15 @@ func (v *vimstate) hoverMsgAt(pos types.Point, tdi protocol.TextDocumentIdentifi
 	}
 	hovRes, err := v.server.Hover(context.Background(), params)
 	if err != nil {
-		return "", fmt.Errorf("failed to get hover details: %v", err)
+		return protocol.MarkupContent{}, fmt.Errorf("failed to get hover details: %v", err)
 	}
 	if hovRes == nil || *hovRes == (protocol.Hover{}) {
-		return "", nil
+		return protocol.MarkupContent{}, nil
 	}
-	return strings.TrimSpace(hovRes.Contents.Value), nil
+	return protocol.MarkupContent{
+		Kind:  hovRes.Contents.Kind,
+		Value: strings.TrimSpace(hovRes.Contents.Value),
+	}, nil
 }
 
 type popupLine struct {
@@ -56,11 +63,7 @@ type popupProp struct {
 }
 
 func (v *vimstate) showHover(posExpr string, opts map[string]interface{}, userOpts *map[string]interface{}) (interface{}, error) {
-	if v.popupWinId > 0 {
-		v.ChannelCall("popup_close", v.popupWinId)
-		v.popupWinId = 0
-		v.ChannelRedraw(false)
-	}
+	v.closeHoverPopup()
 	var vpos struct {
 		BufNum    int `json:"bufnum"`
 		Line      int `json:"line"`
@@ -98,7 +101,7 @@ func (v *vimstate) showHover(posExpr string, opts map[string]interface{}, userOp
 
 	var lines []popupLine
 	if *v.config.HoverDiagnostics {
-		for _, d := range *v.diagnostics() {
+		for _, d := range v.filterDiagnostics(*v.diagnostics(), v.config.DiagnosticsHoverSeverity) {
 			if (b.Num != d.Buf) || !pos.IsWithin(d.Range) {
 				continue
 			}
@@ -109,38 +112,49 @@ func (v *vimstate) showHover(posExpr string, opts map[string]interface{}, userOp
 	if err != nil {
 		return "", err
 	}
-	if msg != "" {
-		for _, l := range strings.Split(msg, "\n") {
+	switch {
+	case msg.Value == "":
+	case msg.Kind == protocol.Markdown:
+		mdLines, links := renderMarkdown(msg.Value)
+		lines = append(lines, mdLines...)
+		v.hoverLinks = links
+	default:
+		for _, l := range strings.Split(msg.Value, "\n") {
 			lines = append(lines, popupLine{l, []popupProp{}})
 		}
+		v.hoverLinks = nil
 	}
 	if len(lines) == 0 {
 		return "", nil
 	}
+	if err := v.openHoverPopup(lines, opts, userOpts, vpos.ScreenPos.Row, vpos.ScreenPos.Col); err != nil {
+		return nil, err
+	}
+	return "", nil
+}
 
+// closeHoverPopup closes the hover popup, if there is one
+func (v *vimstate) closeHoverPopup() {
+	if v.popupWinId > 0 {
+		v.ChannelCall("popup_close", v.popupWinId)
+		v.popupWinId = 0
+		v.ChannelRedraw(false)
+	}
+}
+
+// openHoverPopup opens the hover popup with lines, positioned relative to
+// the screen row and col at which the hover was triggered
+func (v *vimstate) openHoverPopup(lines []popupLine, opts map[string]interface{}, userOpts *map[string]interface{}, row, col int) error {
 	if userOpts != nil {
-		opts = make(map[string]interface{})
-		for k, v := range *userOpts {
-			opts[k] = v
-		}
-		var line, col int64
 		var err error
-		if lv, ok := opts["line"]; ok {
-			if line, err = rawToInt(lv); err != nil {
-				return nil, fmt.Errorf("failed to parse line option: %v", err)
-			}
-		}
-		if cv, ok := opts["col"]; ok {
-			if col, err = rawToInt(cv); err != nil {
-				return nil, fmt.Errorf("failed to parse col option: %v", err)
-			}
+		opts, err = relativePopupOptions(*userOpts, row, col)
+		if err != nil {
+			return err
 		}
-		opts["line"] = line + int64(vpos.ScreenPos.Row)
-		opts["col"] = col + int64(vpos.ScreenPos.Col)
 	} else {
 		opts["pos"] = "botleft"
-		opts["line"] = vpos.ScreenPos.Row - 1
-		opts["col"] = vpos.ScreenPos.Col
+		opts["line"] = row - 1
+		opts["col"] = col
 		opts["mousemoved"] = "any"
 		opts["moved"] = "any"
 		opts["padding"] = []int{0, 1, 0, 1}
@@ -149,7 +163,48 @@ func (v *vimstate) showHover(posExpr string, opts map[string]interface{}, userOp
 	}
 	v.popupWinId = v.ParseInt(v.ChannelCall("popup_create", lines, opts))
 	v.ChannelRedraw(false)
-	return "", nil
+	return nil
+}
+
+func (v *vimstate) hoverLink(flags govim.CommandFlags, args ...string) error {
+	n := 1
+	if len(args) == 1 {
+		var err error
+		n, err = strconv.Atoi(args[0])
+		if err != nil {
+			return fmt.Errorf("failed to parse link number %q: %v", args[0], err)
+		}
+	}
+	if n < 1 || n > len(v.hoverLinks) {
+		return fmt.Errorf("no link %v in the last hover popup", n)
+	}
+	v.ChannelCall("netrw#BrowseX", v.hoverLinks[n-1], 0)
+	return nil
+}
+
+// relativePopupOptions returns a copy of the user-supplied popup options
+// userOpts, with the line and col options, which are relative, offset by the
+// screen row and col at which the popup was triggered
+func relativePopupOptions(userOpts map[string]interface{}, row, col int) (map[string]interface{}, error) {
+	opts := make(map[string]interface{})
+	for k, v := range userOpts {
+		opts[k] = v
+	}
+	var line, column int64
+	var err error
+	if lv, ok := opts["line"]; ok {
+		if line, err = rawToInt(lv); err != nil {
+			return nil, fmt.Errorf("failed to parse line option: %v", err)
+		}
+	}
+	if cv, ok := opts["col"]; ok {
+		if column, err = rawToInt(cv); err != nil {
+			return nil, fmt.Errorf("failed to parse col option: %v", err)
+		}
+	}
+	opts["line"] = line + int64(row)
+	opts["col"] = column + int64(col)
+	return opts, nil
 }
 
 func rawToInt(i interface{}) (int64, error) {
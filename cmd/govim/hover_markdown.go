@@ -0,0 +1,309 @@
+package main
+
+import (
+	"fmt"
+	"go/scanner"
+	"go/token"
+	gotypes "go/types"
+	"regexp"
+	"strings"
+
+	"github.com/govim/govim/cmd/govim/config"
+)
+
+// hoverMarkdownHighlights are the text property types used to render
+// Markdown in the hover popup
+var hoverMarkdownHighlights = []config.Highlight{
+	config.HighlightHoverHeading,
+	config.HighlightHoverEmphasis,
+	config.HighlightHoverStrong,
+	config.HighlightHoverCode,
+	config.HighlightHoverLink,
+	config.HighlightHoverCodeKeyword,
+	config.HighlightHoverCodeType,
+	config.HighlightHoverCodeString,
+	config.HighlightHoverCodeNumber,
+	config.HighlightHoverCodeComment,
+}
+
+// markdownIndent is the prefix gopls uses for preformatted lines in doc
+// comments
+const markdownIndent = "&nbsp;&nbsp;&nbsp;&nbsp;"
+
+var markdownHeading = regexp.MustCompile(`^#{1,6}[ \t]+`)
+
+var markdownEntities = strings.NewReplacer(
+	"&nbsp;", " ",
+	"&lt;", "<",
+	"&gt;", ">",
+	"&quot;", `"`,
+	"&amp;", "&",
+)
+
+// renderMarkdown renders md, the Markdown contents of a hover response, as
+// popup lines. Fenced Go code blocks and preformatted lines are highlighted
+// as Go, headings and emphasis are marked with text properties, and links
+// are numbered and returned, such that they can be listed in a footer.
+// Paragraphs that consist only of links are dropped, on the basis that the
+// footer lists them.
+func renderMarkdown(md string) (lines []popupLine, links []string) {
+	// Blank lines are collapsed, and dropped from the start and end. gopls
+	// follows each preformatted line with a blank line, hence a single blank
+	// line between preformatted lines is also dropped.
+	var blanks int
+	var lastPre bool
+	add := func(l popupLine, pre bool) {
+		if len(lines) > 0 && (blanks > 1 || blanks == 1 && !(pre && lastPre)) {
+			lines = append(lines, popupLine{Text: "", Props: []popupProp{}})
+		}
+		blanks = 0
+		lastPre = pre
+		lines = append(lines, l)
+	}
+	var inFence bool
+	var lang string
+	for _, l := range strings.Split(md, "\n") {
+		trimmed := strings.TrimSpace(l)
+		switch {
+		case strings.HasPrefix(trimmed, "```"):
+			inFence = !inFence
+			lang = strings.TrimSpace(strings.TrimPrefix(trimmed, "```"))
+		case inFence:
+			if lang == "" || lang == "go" {
+				lines = append(lines, goCodeLine(l))
+			} else {
+				lines = append(lines, popupLine{Text: l, Props: []popupProp{}})
+			}
+			blanks, lastPre = 0, false
+		case trimmed == "":
+			blanks++
+		case strings.HasPrefix(l, markdownIndent):
+			add(goCodeLine(markdownEntities.Replace(l)), true)
+		default:
+			heading := markdownHeading.FindString(l)
+			r := markdownRenderer{links: &links, props: []popupProp{}}
+			r.render(strings.TrimPrefix(l, heading))
+			if r.onlyLinks() {
+				continue
+			}
+			line := popupLine{Text: r.buf.String(), Props: r.props}
+			if heading != "" && line.Text != "" {
+				line.Props = append([]popupProp{{
+					Type:   string(config.HighlightHoverHeading),
+					Col:    1,
+					Length: len(line.Text),
+				}}, line.Props...)
+			}
+			add(line, false)
+		}
+	}
+	if len(links) > 0 {
+		lines = append(lines, popupLine{Text: "", Props: []popupProp{}})
+		for i, l := range links {
+			prefix := fmt.Sprintf("[%d] ", i+1)
+			lines = append(lines, popupLine{
+				Text: prefix + l,
+				Props: []popupProp{{
+					Type:   string(config.HighlightHoverLink),
+					Col:    len(prefix) + 1,
+					Length: len(l),
+				}},
+			})
+		}
+	}
+	return lines, links
+}
+
+// goCodeLine returns a popup line for text, a line of Go code, with
+// keywords, predeclared types, literals and comments highlighted
+func goCodeLine(text string) popupLine {
+	props := []popupProp{}
+	fset := token.NewFileSet()
+	f := fset.AddFile("", -1, len(text))
+	var s scanner.Scanner
+	// A single line of code is often not valid Go in its own right, hence we
+	// ignore errors
+	s.Init(f, []byte(text), nil, scanner.ScanComments)
+	for {
+		pos, tok, lit := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+		var hi config.Highlight
+		length := len(lit)
+		switch {
+		case tok.IsKeyword():
+			hi = config.HighlightHoverCodeKeyword
+			length = len(tok.String())
+		case tok == token.STRING || tok == token.CHAR:
+			hi = config.HighlightHoverCodeString
+		case tok == token.INT || tok == token.FLOAT || tok == token.IMAG:
+			hi = config.HighlightHoverCodeNumber
+		case tok == token.COMMENT:
+			hi = config.HighlightHoverCodeComment
+		case tok == token.IDENT:
+			if _, ok := gotypes.Universe.Lookup(lit).(*gotypes.TypeName); !ok {
+				continue
+			}
+			hi = config.HighlightHoverCodeType
+		default:
+			continue
+		}
+		props = append(props, popupProp{Type: string(hi), Col: f.Offset(pos) + 1, Length: length})
+	}
+	return popupLine{Text: text, Props: props}
+}
+
+// markdownRenderer renders the inline Markdown of a single line
+type markdownRenderer struct {
+	buf   strings.Builder
+	props []popupProp
+
+	// links accumulates the link targets seen across all lines
+	links *[]string
+
+	// linkLen is the number of bytes of buf that are the text of links,
+	// including their numbered references
+	linkLen int
+}
+
+// onlyLinks reports whether the rendered line consists only of links
+func (m *markdownRenderer) onlyLinks() bool {
+	return m.linkLen > 0 && len(strings.Join(strings.Fields(m.buf.String()), "")) == m.linkLen
+}
+
+func (m *markdownRenderer) prop(hi config.Highlight, start int) {
+	if l := m.buf.Len() - start; l > 0 {
+		m.props = append(m.props, popupProp{Type: string(hi), Col: start + 1, Length: l})
+	}
+}
+
+func (m *markdownRenderer) render(s string) {
+	emStart, strongStart := -1, -1
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s) && isASCIIPunct(s[i+1]):
+			m.buf.WriteByte(s[i+1])
+			i += 2
+			continue
+		case c == '&':
+			if j := strings.IndexByte(s[i:], ';'); j > 0 {
+				if r := markdownEntities.Replace(s[i : i+j+1]); r != s[i:i+j+1] {
+					m.buf.WriteString(r)
+					i += j + 1
+					continue
+				}
+			}
+		case c == '`':
+			if j := strings.IndexByte(s[i+1:], '`'); j >= 0 {
+				start := m.buf.Len()
+				m.buf.WriteString(s[i+1 : i+1+j])
+				m.prop(config.HighlightHoverCode, start)
+				i += j + 2
+				continue
+			}
+		case (c == '*' || c == '_') && i+1 < len(s) && s[i+1] == c:
+			if strongStart >= 0 {
+				m.prop(config.HighlightHoverStrong, strongStart)
+				strongStart = -1
+				i += 2
+				continue
+			}
+			if strings.Contains(s[i+2:], s[i:i+2]) && emphasisOpens(s, i, 2) {
+				strongStart = m.buf.Len()
+				i += 2
+				continue
+			}
+		case c == '*' || c == '_':
+			if emStart >= 0 && emphasisCloses(s, i) {
+				m.prop(config.HighlightHoverEmphasis, emStart)
+				emStart = -1
+				i++
+				continue
+			}
+			if emStart < 0 && strings.IndexByte(s[i+1:], c) >= 0 && emphasisOpens(s, i, 1) {
+				emStart = m.buf.Len()
+				i++
+				continue
+			}
+		case c == '[':
+			if text, url, n, ok := markdownLink(s[i:]); ok {
+				start := m.buf.Len()
+				m.render(text)
+				*m.links = append(*m.links, url)
+				m.prop(config.HighlightHoverLink, start)
+				fmt.Fprintf(&m.buf, "[%d]", len(*m.links))
+				m.linkLen += len(strings.Join(strings.Fields(m.buf.String()[start:]), ""))
+				i += n
+				continue
+			}
+		}
+		m.buf.WriteByte(c)
+		i++
+	}
+}
+
+// emphasisOpens reports whether the n emphasis markers at s[i] open
+// emphasis. Following CommonMark, underscores do not open emphasis within a
+// word, e.g. snake_case.
+func emphasisOpens(s string, i, n int) bool {
+	if i+n >= len(s) || s[i+n] == ' ' {
+		return false
+	}
+	return s[i] != '_' || i == 0 || !isWordByte(s[i-1])
+}
+
+// emphasisCloses reports whether the emphasis marker at s[i] closes
+// emphasis
+func emphasisCloses(s string, i int) bool {
+	if i == 0 || s[i-1] == ' ' {
+		return false
+	}
+	return s[i] != '_' || i+1 == len(s) || !isWordByte(s[i+1])
+}
+
+// markdownLink parses an inline link of the form [text](url) at the start
+// of s, returning the link text, the unescaped url and the number of bytes
+// consumed
+func markdownLink(s string) (text, url string, n int, ok bool) {
+	depth := 0
+	end := -1
+	for i := 0; i < len(s) && end < 0; i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				end = i
+			}
+		}
+	}
+	if end < 0 || end+1 >= len(s) || s[end+1] != '(' {
+		return "", "", 0, false
+	}
+	var u strings.Builder
+	for i := end + 2; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\\' && i+1 < len(s) && isASCIIPunct(s[i+1]):
+			i++
+			u.WriteByte(s[i])
+		case c == ')':
+			return s[1:end], u.String(), i + 1, true
+		default:
+			u.WriteByte(c)
+		}
+	}
+	return "", "", 0, false
+}
+
+func isASCIIPunct(c byte) bool {
+	return strings.IndexByte("!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~", c) >= 0
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || '0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || c >= 0x80
+}
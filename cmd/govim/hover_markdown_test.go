@@ -0,0 +1,139 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/govim/govim/cmd/govim/config"
+)
+
+func prop(hi config.Highlight, col, length int) popupProp {
+	return popupProp{Type: string(hi), Col: col, Length: length}
+}
+
+var renderMarkdownTests = []struct {
+	name  string
+	md    string
+	lines []popupLine
+	links []string
+}{
+	{
+		name: "heading",
+		md:   "### Usage",
+		lines: []popupLine{
+			{Text: "Usage", Props: []popupProp{prop(config.HighlightHoverHeading, 1, 5)}},
+		},
+	},
+	{
+		name: "emphasis",
+		md:   "a *b* **c** _d_ snake_case_name",
+		lines: []popupLine{
+			{Text: "a b c d snake_case_name", Props: []popupProp{
+				prop(config.HighlightHoverEmphasis, 3, 1),
+				prop(config.HighlightHoverStrong, 5, 1),
+				prop(config.HighlightHoverEmphasis, 7, 1),
+			}},
+		},
+	},
+	{
+		name: "code span and escapes",
+		md:   "call `f(x)` with \\*x\\* &lt; 5",
+		lines: []popupLine{
+			{Text: "call f(x) with *x* < 5", Props: []popupProp{prop(config.HighlightHoverCode, 6, 4)}},
+		},
+	},
+	{
+		name: "links",
+		md:   "See [the docs](https://example.com/docs) for details.\n\n[`T` on pkg.go.dev](https://pkg.go.dev/p#T)",
+		lines: []popupLine{
+			{Text: "See the docs[1] for details.", Props: []popupProp{prop(config.HighlightHoverLink, 5, 8)}},
+			{Text: "", Props: []popupProp{}},
+			{Text: "[1] https://example.com/docs", Props: []popupProp{prop(config.HighlightHoverLink, 5, 24)}},
+			{Text: "[2] https://pkg.go.dev/p#T", Props: []popupProp{prop(config.HighlightHoverLink, 5, 22)}},
+		},
+		links: []string{"https://example.com/docs", "https://pkg.go.dev/p#T"},
+	},
+	{
+		name: "code block and blank lines",
+		md:   "```go\nfunc f() int\n```\n\n\n\nf returns:\n\n&nbsp;&nbsp;&nbsp;&nbsp;return 1\n\n&nbsp;&nbsp;&nbsp;&nbsp;// one\n\n",
+		lines: []popupLine{
+			{Text: "func f() int", Props: []popupProp{
+				prop(config.HighlightHoverCodeKeyword, 1, 4),
+				prop(config.HighlightHoverCodeType, 10, 3),
+			}},
+			{Text: "", Props: []popupProp{}},
+			{Text: "f returns:", Props: []popupProp{}},
+			{Text: "", Props: []popupProp{}},
+			{Text: "    return 1", Props: []popupProp{
+				prop(config.HighlightHoverCodeKeyword, 5, 6),
+				prop(config.HighlightHoverCodeNumber, 12, 1),
+			}},
+			{Text: "    // one", Props: []popupProp{prop(config.HighlightHoverCodeComment, 5, 6)}},
+		},
+	},
+	{
+		name: "other language code block",
+		md:   "```sh\nif true; then\n```",
+		lines: []popupLine{
+			{Text: "if true; then", Props: []popupProp{}},
+		},
+	},
+}
+
+func TestRenderMarkdown(t *testing.T) {
+	for _, tt := range renderMarkdownTests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines, links := renderMarkdown(tt.md)
+			if !reflect.DeepEqual(lines, tt.lines) {
+				t.Errorf("got lines %#v; want %#v", lines, tt.lines)
+			}
+			if !reflect.DeepEqual(links, tt.links) {
+				t.Errorf("got links %q; want %q", links, tt.links)
+			}
+		})
+	}
+}
+
+var goCodeLineTests = []struct {
+	text  string
+	props []popupProp
+}{
+	{
+		text: "func (t T) String() string",
+		props: []popupProp{
+			prop(config.HighlightHoverCodeKeyword, 1, 4),
+			prop(config.HighlightHoverCodeType, 21, 6),
+		},
+	},
+	{
+		text: `const c = "a" + 'b' + 1.5 // c`,
+		props: []popupProp{
+			prop(config.HighlightHoverCodeKeyword, 1, 5),
+			prop(config.HighlightHoverCodeString, 11, 3),
+			prop(config.HighlightHoverCodeString, 17, 3),
+			prop(config.HighlightHoverCodeNumber, 23, 3),
+			prop(config.HighlightHoverCodeComment, 27, 4),
+		},
+	},
+	{
+		// Not valid Go in its own right
+		text: "x error)",
+		props: []popupProp{
+			prop(config.HighlightHoverCodeType, 3, 5),
+		},
+	},
+	{
+		text:  "",
+		props: []popupProp{},
+	},
+}
+
+func TestGoCodeLine(t *testing.T) {
+	for _, tt := range goCodeLineTests {
+		got := goCodeLine(tt.text)
+		want := popupLine{Text: tt.text, Props: tt.props}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("goCodeLine(%q) = %#v; want %#v", tt.text, got, want)
+		}
+	}
+}
@@ -161,6 +161,16 @@ func PointFromVim(b *Buffer, line, col int) (Point, error) {
 	return res, nil
 }
 
+// PointFromOffset returns the Point at the 0-indexed byte offset within b
+func PointFromOffset(b *Buffer, offset int) (Point, error) {
+	cc := b.tokenConvertor()
+	line, col, err := cc.ToPosition(offset)
+	if err != nil {
+		return Point{}, fmt.Errorf("failed to calculate position within buffer %v: %v", b.Num, err)
+	}
+	return PointFromVim(b, line, col)
+}
+
 func PointFromPosition(b *Buffer, pos protocol.Position) (Point, error) {
 	cc := b.tokenConvertor()
 	sline := f2int(pos.Line) + 1
@@ -295,10 +305,41 @@ var SeverityHoverHighlight = map[Severity]config.Highlight{
 	SeverityHint: config.HighlightHoverHint,
 }
 
+// SeverityInlineHighlight returns corresponding inline highlight name for a
+// severity.
+var SeverityInlineHighlight = map[Severity]config.Highlight{
+	SeverityErr:  config.HighlightInlineErr,
+	SeverityWarn: config.HighlightInlineWarn,
+	SeverityInfo: config.HighlightInlineInfo,
+	SeverityHint: config.HighlightInlineHint,
+}
+
+// SeverityFromConfig maps the severities used in config to the
+// corresponding severity.
+var SeverityFromConfig = map[config.Severity]Severity{
+	config.SeverityError:   SeverityErr,
+	config.SeverityWarning: SeverityWarn,
+	config.SeverityInfo:    SeverityInfo,
+	config.SeverityHint:    SeverityHint,
+}
+
 // TextPropID is the govim internal mapping of ID used when adding/removing text properties
 type TextPropID int
 
 const (
 	DiagnosticTextPropID = 0
 	ReferencesTextPropID = 1
+
+	// CodeLensTextPropID is the base ID for code lens text properties. Each
+	// code lens popup is attached to its own text property, which therefore
+	// needs a unique ID: CodeLensTextPropID plus the line number
+	CodeLensTextPropID = 1 << 20
+
+	// SnippetTextPropID is the base ID for the text properties that mark the
+	// tab stops of a snippet: SnippetTextPropID plus the index of the tab stop
+	SnippetTextPropID = 2 << 20
+
+	// SemanticTextPropID is the ID of the text properties used for semantic
+	// highlighting
+	SemanticTextPropID = 3 << 20
 )
@@ -25,6 +25,30 @@ type VimConfig struct {
 	ExperimentalMouseTriggeredHoverPopupOptions  *map[string]interface{}
 	ExperimentalCursorTriggeredHoverPopupOptions *map[string]interface{}
 	ExperimentalWorkaroundCompleteoptLongest     *int
+	SignatureHelp                                *int
+	ExperimentalSignatureHelpPopupOptions        *map[string]interface{}
+	CodeLens                                     *int
+	ProgressPopup                                *int
+	ShowMessageRequestTimeout                    *string
+	GoplsCrashPolicy                             *config.GoplsCrashPolicy
+	CompletionSnippets                           *int
+	CompletionAsync                              *int
+	GoToMultipleLocations                        *config.GoToMultipleLocations
+	RenamePreview                                *int
+	MultiFileEditStrategy                        *config.MultiFileEditStrategy
+	FoldingRanges                                *int
+	FoldingRangeKinds                            *[]config.FoldingRangeKind
+	SemanticHighlighting                         *int
+	DiagnosticsQuickfixSeverity                  *config.Severity
+	DiagnosticsSignsSeverity                     *config.Severity
+	DiagnosticsHighlightSeverity                 *config.Severity
+	DiagnosticsHoverSeverity                     *config.Severity
+	DiagnosticsIncludeSources                    *[]string
+	DiagnosticsExcludeSources                    *[]string
+	DiagnosticsExcludePaths                      *[]string
+	DiagnosticsList                              *config.DiagnosticsList
+	InlineDiagnostics                            *int
+	DiagnosticsInlineSeverity                    *config.Severity
 }
 
 func (c *VimConfig) ToConfig(d config.Config) config.Config {
@@ -47,6 +71,30 @@ func (c *VimConfig) ToConfig(d config.Config) config.Config {
 		ExperimentalMouseTriggeredHoverPopupOptions:  copyMap(c.ExperimentalMouseTriggeredHoverPopupOptions, d.ExperimentalMouseTriggeredHoverPopupOptions),
 		ExperimentalCursorTriggeredHoverPopupOptions: copyMap(c.ExperimentalCursorTriggeredHoverPopupOptions, d.ExperimentalCursorTriggeredHoverPopupOptions),
 		ExperimentalWorkaroundCompleteoptLongest:     boolVal(c.ExperimentalWorkaroundCompleteoptLongest, d.ExperimentalWorkaroundCompleteoptLongest),
+		SignatureHelp:                                boolVal(c.SignatureHelp, d.SignatureHelp),
+		ExperimentalSignatureHelpPopupOptions:        copyMap(c.ExperimentalSignatureHelpPopupOptions, d.ExperimentalSignatureHelpPopupOptions),
+		CodeLens:                                     boolVal(c.CodeLens, d.CodeLens),
+		ProgressPopup:                                boolVal(c.ProgressPopup, d.ProgressPopup),
+		ShowMessageRequestTimeout:                    stringVal(c.ShowMessageRequestTimeout, d.ShowMessageRequestTimeout),
+		GoplsCrashPolicy:                             c.GoplsCrashPolicy,
+		CompletionSnippets:                           boolVal(c.CompletionSnippets, d.CompletionSnippets),
+		CompletionAsync:                              boolVal(c.CompletionAsync, d.CompletionAsync),
+		GoToMultipleLocations:                        c.GoToMultipleLocations,
+		RenamePreview:                                boolVal(c.RenamePreview, d.RenamePreview),
+		MultiFileEditStrategy:                        c.MultiFileEditStrategy,
+		FoldingRanges:                                boolVal(c.FoldingRanges, d.FoldingRanges),
+		FoldingRangeKinds:                            copyFoldingRangeKinds(c.FoldingRangeKinds, d.FoldingRangeKinds),
+		SemanticHighlighting:                         boolVal(c.SemanticHighlighting, d.SemanticHighlighting),
+		DiagnosticsQuickfixSeverity:                  c.DiagnosticsQuickfixSeverity,
+		DiagnosticsSignsSeverity:                     c.DiagnosticsSignsSeverity,
+		DiagnosticsHighlightSeverity:                 c.DiagnosticsHighlightSeverity,
+		DiagnosticsHoverSeverity:                     c.DiagnosticsHoverSeverity,
+		DiagnosticsIncludeSources:                    copyStrings(c.DiagnosticsIncludeSources, d.DiagnosticsIncludeSources),
+		DiagnosticsExcludeSources:                    copyStrings(c.DiagnosticsExcludeSources, d.DiagnosticsExcludeSources),
+		DiagnosticsExcludePaths:                      copyStrings(c.DiagnosticsExcludePaths, d.DiagnosticsExcludePaths),
+		DiagnosticsList:                              c.DiagnosticsList,
+		InlineDiagnostics:                            boolVal(c.InlineDiagnostics, d.InlineDiagnostics),
+		DiagnosticsInlineSeverity:                    c.DiagnosticsInlineSeverity,
 	}
 	if v.FormatOnSave == nil {
 		v.FormatOnSave = d.FormatOnSave
@@ -54,6 +102,33 @@ func (c *VimConfig) ToConfig(d config.Config) config.Config {
 	if v.CompletionMatcher == nil {
 		v.CompletionMatcher = d.CompletionMatcher
 	}
+	if v.GoplsCrashPolicy == nil {
+		v.GoplsCrashPolicy = d.GoplsCrashPolicy
+	}
+	if v.GoToMultipleLocations == nil {
+		v.GoToMultipleLocations = d.GoToMultipleLocations
+	}
+	if v.MultiFileEditStrategy == nil {
+		v.MultiFileEditStrategy = d.MultiFileEditStrategy
+	}
+	if v.DiagnosticsList == nil {
+		v.DiagnosticsList = d.DiagnosticsList
+	}
+	if v.DiagnosticsQuickfixSeverity == nil {
+		v.DiagnosticsQuickfixSeverity = d.DiagnosticsQuickfixSeverity
+	}
+	if v.DiagnosticsSignsSeverity == nil {
+		v.DiagnosticsSignsSeverity = d.DiagnosticsSignsSeverity
+	}
+	if v.DiagnosticsHighlightSeverity == nil {
+		v.DiagnosticsHighlightSeverity = d.DiagnosticsHighlightSeverity
+	}
+	if v.DiagnosticsHoverSeverity == nil {
+		v.DiagnosticsHoverSeverity = d.DiagnosticsHoverSeverity
+	}
+	if v.DiagnosticsInlineSeverity == nil {
+		v.DiagnosticsInlineSeverity = d.DiagnosticsInlineSeverity
+	}
 	return v
 }
 
@@ -102,14 +177,70 @@ func copyMap(i, j *map[string]interface{}) *map[string]interface{} {
 	return &res
 }
 
+func copyFoldingRangeKinds(i, j *[]config.FoldingRangeKind) *[]config.FoldingRangeKind {
+	toCopy := i
+	if i == nil {
+		toCopy = j
+		if j == nil {
+			return nil
+		}
+	}
+	res := append([]config.FoldingRangeKind{}, *toCopy...)
+	return &res
+}
+
+func copyStrings(i, j *[]string) *[]string {
+	toCopy := i
+	if i == nil {
+		toCopy = j
+		if j == nil {
+			return nil
+		}
+	}
+	res := append([]string{}, *toCopy...)
+	return &res
+}
+
 func FormatOnSaveVal(v config.FormatOnSave) *config.FormatOnSave {
 	return &v
 }
 
+func GoplsCrashPolicyVal(v config.GoplsCrashPolicy) *config.GoplsCrashPolicy {
+	return &v
+}
+
+func GoToMultipleLocationsVal(v config.GoToMultipleLocations) *config.GoToMultipleLocations {
+	return &v
+}
+
+func MultiFileEditStrategyVal(v config.MultiFileEditStrategy) *config.MultiFileEditStrategy {
+	return &v
+}
+
+func FoldingRangeKindsVal(v ...config.FoldingRangeKind) *[]config.FoldingRangeKind {
+	return &v
+}
+
+func DiagnosticsListVal(v config.DiagnosticsList) *config.DiagnosticsList {
+	return &v
+}
+
+func SeverityVal(v config.Severity) *config.Severity {
+	return &v
+}
+
+func StringsVal(v ...string) *[]string {
+	return &v
+}
+
 func BoolVal(v bool) *bool {
 	return &v
 }
 
+func StringVal(v string) *string {
+	return &v
+}
+
 func MapVal(v map[string]interface{}) *map[string]interface{} {
 	return &v
 }
@@ -126,3 +257,50 @@ func EqualBool(i, j *bool) bool {
 	}
 	return *i == *j
 }
+
+func EqualFoldingRangeKinds(i, j *[]config.FoldingRangeKind) bool {
+	if i == nil && j == nil {
+		return true
+	}
+	if i == nil && j != nil ||
+		i != nil && j == nil ||
+		len(*i) != len(*j) {
+		return false
+	}
+	for k := range *i {
+		if (*i)[k] != (*j)[k] {
+			return false
+		}
+	}
+	return true
+}
+
+// EqualSeverity returns true iff i and j are both nil, or if both are non-nil
+// and dereference to the same value. Otherwise it returns false.
+func EqualSeverity(i, j *config.Severity) bool {
+	if i == nil && j == nil {
+		return true
+	}
+	if i == nil && j != nil ||
+		i != nil && j == nil {
+		return false
+	}
+	return *i == *j
+}
+
+func EqualStrings(i, j *[]string) bool {
+	if i == nil && j == nil {
+		return true
+	}
+	if i == nil && j != nil ||
+		i != nil && j == nil ||
+		len(*i) != len(*j) {
+		return false
+	}
+	for k := range *i {
+		if (*i)[k] != (*j)[k] {
+			return false
+		}
+	}
+	return true
+}
@@ -144,18 +144,60 @@ type govimplugin struct {
 	// set in os/exec.Command.Env
 	goplsEnv []string
 
-	goplspath   string
+	goplspath string
+
+	// gopls and the fields that follow refer to the current gopls. They are
+	// replaced on the Vim thread when gopls is restarted. If a restart fails
+	// they are nil, other than server which is a notRunningServer. Hence a
+	// goroutine that calls gopls must use a server captured on the Vim thread,
+	// rather than reading server itself.
 	gopls       *os.Process
 	goplsConn   *jsonrpc2.Conn
 	goplsCancel context.CancelFunc
 	goplsStdin  io.WriteCloser
 	server      protocol.Server
 
+	// semanticTokensCaps are the semantic tokens capabilities of the current
+	// gopls, or nil if it does not support semantic tokens
+	semanticTokensCaps *semanticTokensCapabilities
+
+	// goplsStop is closed when the current gopls is deliberately stopped
+	// (i.e. restarted), and goplsDone is closed once it has exited
+	goplsStop chan struct{}
+	goplsDone chan struct{}
+
+	// goplsFatalOnce ensures that at most one fatal error is sent on errCh.
+	// See goplsFatal
+	goplsFatalOnce sync.Once
+
+	// goplsFatalLogOnly is set in tests that deliberately crash gopls, in
+	// which case goplsFatal only logs the error it would otherwise report.
+	// It is guarded by goplsFatalLock
+	goplsFatalLock    sync.Mutex
+	goplsFatalLogOnly bool
+
+	// goplsCrashes are the times at which gopls recently exited
+	// unexpectedly. See goplsExited
+	goplsCrashes []time.Time
+
 	isGui bool
 
+	// hasTextPropText is set if Vim supports text properties with text, as
+	// used by config.Config.InlineDiagnostics
+	hasTextPropText bool
+
 	tomb tomb.Tomb
 
-	modWatcher *modWatcher
+	// workspaceLock protects access to workspaceFolders and modWatchers
+	workspaceLock sync.Mutex
+
+	// workspaceFolders are the current workspace folders, in the order in
+	// which they were added
+	workspaceFolders []workspaceFolder
+
+	// modWatchers are the file watchers for the modules of the workspace
+	// folders, keyed by go.mod path
+	modWatchers map[string]*modWatcher
 
 	// diagnosticsChangedLock protects access to rawDiagnostics,
 	// diagnosticsChanged, diagnosticsChangedQuickfix,
@@ -172,6 +214,18 @@ type govimplugin struct {
 	// when updating the quickfix window
 	lastDiagnosticsQuickfix *[]types.Diagnostic
 
+	// lastDiagnosticsLocationLists records the last diagnostics that were
+	// used when updating location lists (see config.DiagnosticsList)
+	lastDiagnosticsLocationLists *[]types.Diagnostic
+
+	// lastDiagnosticsInline records the last diagnostics that were used
+	// when updating inline diagnostics
+	lastDiagnosticsInline *[]types.Diagnostic
+
+	// lastDiagnosticsAutocmd records the last diagnostics for which the
+	// User GOVIMDiagnosticsChanged autocommand was triggered
+	lastDiagnosticsAutocmd *[]types.Diagnostic
+
 	// lastDiagnosticsSigns records the last diagnostics that were used when
 	// updating signs
 	lastDiagnosticsSigns *[]types.Diagnostic
@@ -192,6 +246,15 @@ type govimplugin struct {
 
 	bufferUpdates chan *bufferUpdate
 
+	// progressLock protects access to progress and progressSeq
+	progressLock sync.Mutex
+
+	// progress is the state of gopls work done progress, keyed by token
+	progress map[string]*workDoneProgress
+
+	// progressSeq is the sequence number of the next progress
+	progressSeq int
+
 	// inShutdown is closed when govim is told to Shutdown
 	inShutdown chan struct{}
 }
@@ -215,6 +278,35 @@ func newplugin(goplspath string, goplsEnv []string, defaults, user *config.Confi
 			HoverDiagnostics:                  vimconfig.BoolVal(true),
 			TempModfile:                       vimconfig.BoolVal(false),
 			ExperimentalAutoreadLoadedBuffers: vimconfig.BoolVal(false),
+			SignatureHelp:                     vimconfig.BoolVal(false),
+			CodeLens:                          vimconfig.BoolVal(false),
+			ProgressPopup:                     vimconfig.BoolVal(false),
+			ShowMessageRequestTimeout:         vimconfig.StringVal("30s"),
+			GoplsCrashPolicy:                  vimconfig.GoplsCrashPolicyVal(config.GoplsCrashPolicyRestart),
+			CompletionSnippets:                vimconfig.BoolVal(false),
+			CompletionAsync:                   vimconfig.BoolVal(false),
+			GoToMultipleLocations:             vimconfig.GoToMultipleLocationsVal(config.GoToMultipleLocationsPopup),
+			RenamePreview:                     vimconfig.BoolVal(false),
+			MultiFileEditStrategy:             vimconfig.MultiFileEditStrategyVal(config.MultiFileEditStrategySplit),
+			FoldingRanges:                     vimconfig.BoolVal(false),
+			FoldingRangeKinds: vimconfig.FoldingRangeKindsVal(
+				config.FoldingRangeKindImports,
+				config.FoldingRangeKindComment,
+				config.FoldingRangeKindFunction,
+				config.FoldingRangeKindComposite,
+				config.FoldingRangeKindOther,
+			),
+			SemanticHighlighting:         vimconfig.BoolVal(false),
+			DiagnosticsQuickfixSeverity:  vimconfig.SeverityVal(config.SeverityHint),
+			DiagnosticsSignsSeverity:     vimconfig.SeverityVal(config.SeverityHint),
+			DiagnosticsHighlightSeverity: vimconfig.SeverityVal(config.SeverityHint),
+			DiagnosticsHoverSeverity:     vimconfig.SeverityVal(config.SeverityHint),
+			DiagnosticsIncludeSources:    vimconfig.StringsVal(),
+			DiagnosticsExcludeSources:    vimconfig.StringsVal(),
+			DiagnosticsExcludePaths:      vimconfig.StringsVal(),
+			DiagnosticsList:              vimconfig.DiagnosticsListVal(config.DiagnosticsListQuickfix),
+			InlineDiagnostics:            vimconfig.BoolVal(false),
+			DiagnosticsInlineSeverity:    vimconfig.SeverityVal(config.SeverityHint),
 		}
 	}
 	// Overlay the initial user values on the defaults
@@ -230,6 +322,8 @@ func newplugin(goplspath string, goplsEnv []string, defaults, user *config.Confi
 		goplspath:        goplspath,
 		Driver:           d,
 		inShutdown:       make(chan struct{}),
+		progress:         make(map[string]*workDoneProgress),
+		modWatchers:      make(map[string]*modWatcher),
 		diagnosticsCache: &emptyDiags,
 		vimstate: &vimstate{
 			Driver:                d,
@@ -238,6 +332,11 @@ func newplugin(goplspath string, goplsEnv []string, defaults, user *config.Confi
 			config:                *defaults,
 			quickfixIsDiagnostics: true,
 			suggestedFixesPopups:  make(map[int][]protocol.WorkspaceEdit),
+			messageRequestPopups:  make(map[int]chan int),
+			codeLenses:            make(map[int]*bufCodeLenses),
+			foldingRanges:         make(map[int]*bufFoldingRanges),
+			semanticTokens:        make(map[int]*bufSemanticTokens),
+			selectionRanges:       make(map[int]*selectionRanges),
 		},
 	}
 	res.vimstate.govimplugin = res
@@ -256,9 +355,15 @@ func (g *govimplugin) Init(gg govim.Govim, errCh chan error) error {
 	g.DefineAutoCommand("", govim.Events{govim.EventBufWritePost}, govim.Patterns{"*.go"}, false, g.vimstate.bufWritePost, "eval(expand('<abuf>'))")
 	g.DefineFunction(string(config.FunctionComplete), []string{"findarg", "base"}, g.vimstate.complete)
 	g.DefineCommand(string(config.CommandGoToDef), g.vimstate.gotoDef, govim.NArgsZeroOrOne)
+	g.DefineCommand(string(config.CommandGoToTypeDef), g.vimstate.gotoTypeDef, govim.NArgsZeroOrOne)
+	g.DefineCommand(string(config.CommandGoToDecl), g.vimstate.gotoDecl, govim.NArgsZeroOrOne)
 	g.DefineCommand(string(config.CommandSuggestedFixes), g.vimstate.suggestFixes, govim.NArgsZeroOrOne)
 	g.DefineCommand(string(config.CommandGoToPrevDef), g.vimstate.gotoPrevDef, govim.NArgsZeroOrOne, govim.CountN(1))
 	g.DefineFunction(string(config.FunctionHover), []string{}, g.vimstate.hover)
+	// BufWinEnter is for all buffers, such that a location list populated
+	// with diagnostics for a Go buffer is updated when a window switches to
+	// another buffer
+	g.DefineAutoCommand("", govim.Events{govim.EventBufWinEnter}, govim.Patterns{"*"}, false, g.vimstate.diagnosticsBufWinEnter)
 	g.DefineAutoCommand("", govim.Events{govim.EventBufDelete}, govim.Patterns{"*.go"}, false, g.vimstate.deleteCurrentBuffer, "eval(expand('<abuf>'))")
 	g.DefineCommand(string(config.CommandGoFmt), g.vimstate.gofmtCurrentBufferRange)
 	g.DefineCommand(string(config.CommandGoImports), g.vimstate.goimportsCurrentBufferRange)
@@ -271,10 +376,41 @@ func (g *govimplugin) Init(gg govim.Govim, errCh chan error) error {
 	g.DefineCommand(string(config.CommandReferences), g.vimstate.references)
 	g.DefineCommand(string(config.CommandImplements), g.vimstate.implements)
 	g.DefineCommand(string(config.CommandRename), g.vimstate.rename, govim.NArgsZeroOrOne)
+	g.DefineFunction(string(config.FunctionRenamePreviewMark), []string{"line", "accept"}, g.vimstate.renamePreviewMark)
+	g.DefineFunction(string(config.FunctionRenamePreviewApply), []string{}, g.vimstate.renamePreviewApply)
+	g.DefineCommand(string(config.CommandUndoEdit), g.vimstate.undoEdit)
+	g.DefineCommand(string(config.CommandRedoEdit), g.vimstate.redoEdit)
 	g.DefineCommand(string(config.CommandStringFn), g.vimstate.stringfns, govim.RangeLine, govim.CompleteCustomList(PluginPrefix+config.FunctionStringFnComplete), govim.NArgsOneOrMore)
 	g.DefineFunction(string(config.FunctionStringFnComplete), []string{"ArgLead", "CmdLine", "CursorPos"}, g.vimstate.stringfncomplete)
 	g.DefineCommand(string(config.CommandHighlightReferences), g.vimstate.highlightReferences)
 	g.DefineCommand(string(config.CommandClearReferencesHighlights), g.vimstate.clearReferencesHighlights)
+	g.DefineCommand(string(config.CommandWorkspaceSymbol), g.vimstate.workspaceSymbol, govim.NArgsZeroOrOne)
+	g.DefineFunction(string(config.FunctionWorkspaceSymbolUpdate), []string{"id", "query"}, g.vimstate.workspaceSymbolUpdate)
+	g.DefineCommand(string(config.CommandOutline), g.vimstate.toggleOutline)
+	g.DefineFunction(string(config.FunctionOutlineJump), []string{"line"}, g.vimstate.outlineJump)
+	g.DefineCommand(string(config.CommandCallersOf), g.vimstate.callersOf)
+	g.DefineCommand(string(config.CommandCalleesOf), g.vimstate.calleesOf)
+	g.DefineFunction(string(config.FunctionCallHierarchyToggle), []string{"line"}, g.vimstate.callHierarchyToggle)
+	g.DefineFunction(string(config.FunctionCallHierarchyJump), []string{"line"}, g.vimstate.callHierarchyJump)
+	g.DefineAutoCommand("", govim.Events{govim.EventCursorMovedI}, govim.Patterns{"*.go"}, false, g.vimstate.signatureHelpCursorMoved, exprSignatureHelpPos)
+	g.DefineAutoCommand("", govim.Events{govim.EventInsertLeave}, govim.Patterns{"*.go"}, false, g.vimstate.signatureHelpInsertLeave)
+	g.DefineCommand(string(config.CommandCodeLens), g.vimstate.codeLens, govim.NArgsZeroOrOne)
+	g.DefineFunction(string(config.FunctionProgress), []string{}, g.vimstate.progressString)
+	g.DefineFunction(string(config.FunctionDiagnosticsCount), []string{"..."}, g.vimstate.diagnosticsCount)
+	g.DefineCommand(string(config.CommandWorkspaceAdd), g.vimstate.workspaceAdd, govim.NArgsZeroOrMore, govim.CompleteDir)
+	g.DefineCommand(string(config.CommandWorkspaceRemove), g.vimstate.workspaceRemove, govim.NArgsOneOrMore, govim.CompleteDir)
+	g.DefineAutoCommand("", govim.Events{govim.EventDirChanged}, govim.Patterns{"*"}, false, g.vimstate.dirChanged, "getcwd(-1)")
+	g.DefineCommand(string(config.CommandGoplsRestart), g.vimstate.goplsRestart)
+	g.DefineCommand(string(config.CommandDiagnosticsFilter), g.vimstate.diagnosticsFilter, govim.NArgsZeroOrOne)
+	g.DefineCommand(string(config.CommandNextDiagnostic), g.vimstate.nextDiagnostic, govim.NArgsZeroOrOne)
+	g.DefineCommand(string(config.CommandPrevDiagnostic), g.vimstate.prevDiagnostic, govim.NArgsZeroOrOne)
+	g.DefineCommand(string(config.CommandHoverLink), g.vimstate.hoverLink, govim.NArgsZeroOrOne)
+	g.DefineAutoCommand("", govim.Events{govim.EventInsertEnter, govim.EventCursorMovedI}, govim.Patterns{"*.go"}, false, g.vimstate.inlineDiagnosticsCursorMovedI, exprInlineDiagnosticsPos)
+	g.DefineAutoCommand("", govim.Events{govim.EventInsertLeave}, govim.Patterns{"*.go"}, false, g.vimstate.inlineDiagnosticsInsertLeave)
+	g.DefineAutoCommand("", govim.Events{govim.EventCompleteChanged}, govim.Patterns{"*.go"}, false, g.vimstate.completeChanged, "v:event")
+	g.DefineFunction(string(config.FunctionSnippetJump), []string{"direction"}, g.vimstate.snippetJump)
+	g.DefineAutoCommand("", govim.Events{govim.EventTextChangedI}, govim.Patterns{"*.go"}, false, g.vimstate.completeTextChangedI, exprAsyncCompletePos)
+	g.DefineAutoCommand("", govim.Events{govim.EventInsertLeave}, govim.Patterns{"*.go"}, false, g.vimstate.completeInsertLeave)
 	g.DefineAutoCommand("", govim.Events{govim.EventCompleteDone}, govim.Patterns{"*.go"}, false, g.vimstate.completeDone, "eval(expand('<abuf>'))", "v:completed_item")
 	g.defineHighlights()
 	if err := g.vimstate.signDefine(); err != nil {
@@ -284,12 +420,16 @@ func (g *govimplugin) Init(gg govim.Govim, errCh chan error) error {
 		return fmt.Errorf("failed to defined text property types: %v", err)
 	}
 	g.DefineFunction(string(config.FunctionMotion), []string{"direction", "target"}, g.vimstate.motion)
+	g.DefineFunction(string(config.FunctionTextObject), []string{"object", "extent"}, g.vimstate.textObject)
+	g.DefineFunction(string(config.FunctionExpandSelection), []string{}, g.vimstate.expandSelection)
+	g.DefineFunction(string(config.FunctionShrinkSelection), []string{}, g.vimstate.shrinkSelection)
 
 	g.startProcessBufferUpdates()
 
 	g.InitTestAPI()
 
 	g.isGui = g.ParseInt(g.ChannelExpr(`has("gui_running")`)) == 1
+	g.hasTextPropText = g.ParseInt(g.ChannelExpr(`has("patch-9.0.0121")`)) == 1
 
 	if err := g.startGopls(); err != nil {
 		return err
@@ -313,17 +453,22 @@ func goModPath(wd string) (string, error) {
 func (g *govimplugin) Shutdown() error {
 	close(g.inShutdown)
 	close(g.bufferUpdates)
-	if err := g.server.Shutdown(context.Background()); err != nil {
-		return fmt.Errorf("failed to call gopls Shutdown: %v", err)
-	}
-	// We "kill" gopls by closing its stdin. Standard practice for processes
-	// that communicate over stdin/stdout is to exit cleanly when stdin is
-	// closed.
-	if err := g.goplsStdin.Close(); err != nil {
-		return fmt.Errorf("failed to close gopls stdin: %v", err)
+	// There is no current gopls if a restart failed
+	if g.goplsStdin != nil {
+		if err := g.server.Shutdown(context.Background()); err != nil {
+			return fmt.Errorf("failed to call gopls Shutdown: %v", err)
+		}
+		// We "kill" gopls by closing its stdin. Standard practice for processes
+		// that communicate over stdin/stdout is to exit cleanly when stdin is
+		// closed.
+		if err := g.goplsStdin.Close(); err != nil {
+			return fmt.Errorf("failed to close gopls stdin: %v", err)
+		}
 	}
-	if g.modWatcher != nil {
-		if err := g.modWatcher.close(); err != nil {
+	g.workspaceLock.Lock()
+	defer g.workspaceLock.Unlock()
+	for _, mw := range g.modWatchers {
+		if err := mw.close(); err != nil {
 			return fmt.Errorf("failed to close file watcher: %v", err)
 		}
 	}
@@ -356,7 +501,39 @@ func (g *govimplugin) defineHighlights() {
 
 		fmt.Sprintf("highlight default %s cterm=none gui=italic ctermfg=%d guifg=#8a8a8a", config.HighlightHoverDiagSrc, diagSrcColor),
 
+		fmt.Sprintf("highlight default link %s Title", config.HighlightHoverHeading),
+		fmt.Sprintf("highlight default %s term=italic cterm=italic gui=italic", config.HighlightHoverEmphasis),
+		fmt.Sprintf("highlight default %s term=bold cterm=bold gui=bold", config.HighlightHoverStrong),
+		fmt.Sprintf("highlight default link %s Special", config.HighlightHoverCode),
+		fmt.Sprintf("highlight default link %s Underlined", config.HighlightHoverLink),
+		fmt.Sprintf("highlight default link %s Keyword", config.HighlightHoverCodeKeyword),
+		fmt.Sprintf("highlight default link %s Type", config.HighlightHoverCodeType),
+		fmt.Sprintf("highlight default link %s String", config.HighlightHoverCodeString),
+		fmt.Sprintf("highlight default link %s Number", config.HighlightHoverCodeNumber),
+		fmt.Sprintf("highlight default link %s Comment", config.HighlightHoverCodeComment),
+
+		fmt.Sprintf("highlight default %s term=italic cterm=italic gui=italic ctermfg=1 guifg=Red", config.HighlightInlineErr),
+		fmt.Sprintf("highlight default %s term=italic cterm=italic gui=italic ctermfg=%d guifg=Orange", config.HighlightInlineWarn, warnColor),
+		fmt.Sprintf("highlight default %s term=italic cterm=italic gui=italic ctermfg=6 guifg=Cyan", config.HighlightInlineInfo),
+		fmt.Sprintf("highlight default link %s %s", config.HighlightInlineHint, config.HighlightInlineInfo),
+
 		fmt.Sprintf("highlight default %s term=reverse cterm=reverse gui=reverse", config.HighlightReferences),
+
+		fmt.Sprintf("highlight default link %s Search", config.HighlightSignatureActiveParameter),
+
+		fmt.Sprintf("highlight default link %s Comment", config.HighlightCodeLens),
+		fmt.Sprintf("highlight default link %s Visual", config.HighlightSnippetPlaceholder),
+
+		fmt.Sprintf("highlight default link %s Include", config.HighlightSemNamespace),
+		fmt.Sprintf("highlight default link %s Type", config.HighlightSemType),
+		fmt.Sprintf("highlight default link %s Identifier", config.HighlightSemParameter),
+		fmt.Sprintf("highlight default %s term=NONE", config.HighlightSemVariable),
+		fmt.Sprintf("highlight default %s term=NONE", config.HighlightSemProperty),
+		fmt.Sprintf("highlight default link %s Function", config.HighlightSemFunction),
+		fmt.Sprintf("highlight default link %s Function", config.HighlightSemMethod),
+		fmt.Sprintf("highlight default link %s Constant", config.HighlightSemReadonly),
+		fmt.Sprintf("highlight default link %s Special", config.HighlightSemDefaultLibrary),
+		fmt.Sprintf("highlight default %s term=strikethrough cterm=strikethrough gui=strikethrough", config.HighlightSemDeprecated),
 	} {
 		g.vimstate.BatchChannelCall("execute", hi)
 	}
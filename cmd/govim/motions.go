@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"go/ast"
 	"go/token"
+	"strings"
 	"unicode/utf8"
 )
 
@@ -15,7 +16,8 @@ func (v *vimstate) motion(args ...json.RawMessage) (interface{}, error) {
 	//
 	// direction is either "previous" or "next" (relative to
 	// the cursor position). target is based as closely as possible on the
-	// definitions in go/ast.
+	// definitions in go/ast, and takes the form Node.Pos() or Node.End(),
+	// where Node is one of the keys of motionNodes.
 	//
 	// For example the call GOVIMMotion("next", "File.Decls.End()") moves the
 	// cursor to the first File Decl end position after the current cursor
@@ -46,6 +48,10 @@ func (v *vimstate) motion(args ...json.RawMessage) (interface{}, error) {
 		return nil, fmt.Errorf("got motion request before buffer had loaded?")
 	}
 	<-b.ASTWait
+	if b.AST == nil {
+		// Nothing we can do here.
+		return nil, nil
+	}
 
 	var file *token.File
 	b.Fset.Iterate(func(f *token.File) bool {
@@ -72,8 +78,10 @@ func (v *vimstate) motion(args ...json.RawMessage) (interface{}, error) {
 		return nil, fmt.Errorf("got unknown direction %q", dir)
 	}
 	var resolv func(n ast.Node) token.Pos
-	switch target {
-	case "File.Decls.End()":
+	var nodeName string
+	switch {
+	case strings.HasSuffix(target, ".End()"):
+		nodeName = strings.TrimSuffix(target, ".End()")
 		resolv = func(n ast.Node) token.Pos {
 			// The user sees themselves as being at the end when the cursor is
 			// before the closing brace, not after. Hence adjust backwards
@@ -87,7 +95,8 @@ func (v *vimstate) motion(args ...json.RawMessage) (interface{}, error) {
 			_, size := utf8.DecodeLastRune(b.Contents()[:offset])
 			return b.Fset.File(pos).Pos(offset - size)
 		}
-	case "File.Decls.Pos()":
+	case strings.HasSuffix(target, ".Pos()"):
+		nodeName = strings.TrimSuffix(target, ".Pos()")
 		resolv = func(n ast.Node) token.Pos {
 			return n.Pos()
 		}
@@ -95,44 +104,112 @@ func (v *vimstate) motion(args ...json.RawMessage) (interface{}, error) {
 		return nil, fmt.Errorf("got unknown target %q", target)
 	}
 
-	// Brute force this for now
-	var targetNode ast.Node
-	switch dir {
-	case "next":
-		for i := 0; i < len(b.AST.Decls); i++ {
-			d := b.AST.Decls[i]
-			resolved := resolv(d)
-			if !resolved.IsValid() {
-				// We can't complete the motion because of an invalid target position.
-				// Likely a result of a syntax error. In future we could improve this
-				// by giving a non-fatal warning message, just silently "fail" for now.
-				return nil, nil
-			}
-			if resolved > pos {
-				targetNode = d
-				break
-			}
+	nodes, ok := motionNodes[nodeName]
+	if !ok {
+		return nil, fmt.Errorf("got unknown target %q", target)
+	}
+
+	// Brute force this for now. Note that a partial AST (the result of
+	// syntax errors) can give rise to invalid positions, which we skip.
+	var targetPos token.Pos
+	for _, n := range nodes(b.AST) {
+		resolved := resolv(n)
+		if !resolved.IsValid() {
+			continue
 		}
-	case "prev":
-		for i := len(b.AST.Decls) - 1; i >= 0; i-- {
-			d := b.AST.Decls[i]
-			resolved := resolv(d)
-			if !resolved.IsValid() {
-				// We can't complete the motion because of an invalid target position.
-				// Likely a result of a syntax error. In future we could improve this
-				// by giving a non-fatal warning message, just silently "fail" for now.
-				return nil, nil
+		switch dir {
+		case "next":
+			if resolved > pos && (!targetPos.IsValid() || resolved < targetPos) {
+				targetPos = resolved
 			}
-			if resolved < pos {
-				targetNode = d
-				break
+		case "prev":
+			if resolved < pos && (!targetPos.IsValid() || resolved > targetPos) {
+				targetPos = resolved
 			}
 		}
 	}
 
-	if targetNode != nil {
-		position := b.Fset.Position(resolv(targetNode))
+	if targetPos.IsValid() {
+		position := b.Fset.Position(targetPos)
 		v.ChannelCall("cursor", position.Line, position.Column)
 	}
 	return nil, nil
 }
+
+// motionNodes are the node targets of GOVIMMotion, each with a function
+// that returns the target nodes within a file
+var motionNodes = map[string]func(f *ast.File) []ast.Node{
+	"File.Decls": func(f *ast.File) (res []ast.Node) {
+		for _, d := range f.Decls {
+			res = append(res, d)
+		}
+		return
+	},
+	"FuncDecl": inspectNodes(func(n ast.Node) bool {
+		fd, ok := n.(*ast.FuncDecl)
+		return ok && fd.Recv == nil
+	}),
+	"MethodDecl": inspectNodes(func(n ast.Node) bool {
+		fd, ok := n.(*ast.FuncDecl)
+		return ok && fd.Recv != nil
+	}),
+	"TypeSpec": inspectNodes(func(n ast.Node) bool {
+		_, ok := n.(*ast.TypeSpec)
+		return ok
+	}),
+	"Field": func(f *ast.File) (res []ast.Node) {
+		// Only struct fields, as opposed to parameters, results etc
+		ast.Inspect(f, func(n ast.Node) bool {
+			if st, ok := n.(*ast.StructType); ok && st.Fields != nil {
+				for _, fld := range st.Fields.List {
+					res = append(res, fld)
+				}
+			}
+			return true
+		})
+		return
+	},
+	"CaseClause": inspectNodes(func(n ast.Node) bool {
+		switch n.(type) {
+		case *ast.CaseClause, *ast.CommClause:
+			return true
+		}
+		return false
+	}),
+	"IfStmt": inspectNodes(func(n ast.Node) bool {
+		_, ok := n.(*ast.IfStmt)
+		return ok
+	}),
+	"ForStmt": inspectNodes(func(n ast.Node) bool {
+		switch n.(type) {
+		case *ast.ForStmt, *ast.RangeStmt:
+			return true
+		}
+		return false
+	}),
+	"ReturnStmt": inspectNodes(func(n ast.Node) bool {
+		_, ok := n.(*ast.ReturnStmt)
+		return ok
+	}),
+	"CommentGroup": func(f *ast.File) (res []ast.Node) {
+		// Comments are not visited by ast.Inspect
+		for _, cg := range f.Comments {
+			res = append(res, cg)
+		}
+		return
+	},
+}
+
+// inspectNodes returns a function that returns the nodes within a file for
+// which match returns true
+func inspectNodes(match func(n ast.Node) bool) func(f *ast.File) []ast.Node {
+	return func(f *ast.File) (res []ast.Node) {
+		ast.Inspect(f, func(n ast.Node) bool {
+			if n != nil && match(n) {
+				res = append(res, n)
+			}
+			return true
+		})
+		return
+	}
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/govim/govim"
+	"github.com/govim/govim/cmd/govim/config"
+	"github.com/govim/govim/cmd/govim/internal/golang_org_x_tools/lsp/protocol"
+	"github.com/govim/govim/cmd/govim/internal/types"
+)
+
+const outlineBufName = "govim-outline"
+
+// outline is the state of the document outline window
+type outline struct {
+	// srcBuf is the number of the Go buffer being outlined
+	srcBuf int
+
+	// bufnr is the number of the scratch buffer that holds the outline
+	bufnr int
+
+	// entries are the flattened symbols of srcBuf, in the order in which
+	// they appear in the outline buffer, i.e. entries[i] is line i+1
+	entries []outlineEntry
+
+	// lines are the current contents of the outline buffer
+	lines []string
+
+	// cancel cancels the in-flight documentSymbol request, if any. It must
+	// only be used on the Vim "thread"
+	cancel context.CancelFunc
+}
+
+type outlineEntry struct {
+	depth int
+	sym   protocol.DocumentSymbol
+
+	// rng is the range of sym in srcBuf. It is the zero value if the range
+	// could not be resolved against the buffer contents
+	rng types.Range
+}
+
+func (v *vimstate) toggleOutline(flags govim.CommandFlags, args ...string) error {
+	o := v.currentOutline()
+	b, _, err := v.cursorPos()
+	if err != nil {
+		if o != nil && v.ParseInt(v.ChannelExpr(`bufnr("")`)) == o.bufnr {
+			// Calling the command from within the outline closes it
+			return v.closeOutline()
+		}
+		return fmt.Errorf("failed to determine cursor position: %v", err)
+	}
+	if o != nil && o.srcBuf == b.Num {
+		return v.closeOutline()
+	}
+	if o == nil {
+		o = &outline{
+			bufnr: v.openScratchWindow(flags.Mods.String(), outlineBufName, "govimoutline",
+				fmt.Sprintf("nnoremap <buffer> <silent> <CR> :call %v%v(line('.'))<CR>", PluginPrefix, config.FunctionOutlineJump),
+			),
+		}
+		v.outline = o
+	}
+	// Either a new outline, or we are retargeting an existing outline at
+	// another buffer
+	if o.cancel != nil {
+		o.cancel()
+		o.cancel = nil
+	}
+	o.srcBuf = b.Num
+	syms, err := documentSymbols(context.Background(), v.server, b)
+	if err != nil {
+		return err
+	}
+	return v.renderOutline(o, b, syms)
+}
+
+// currentOutline returns the outline state if the outline buffer still
+// exists, else nil. The user is free to close the outline window at any
+// point, which in turn wipes the buffer (bufhidden=wipe)
+func (v *vimstate) currentOutline() *outline {
+	o := v.outline
+	if o == nil {
+		return nil
+	}
+	if v.ParseInt(v.ChannelCall("bufexists", o.bufnr)) == 0 {
+		if o.cancel != nil {
+			o.cancel()
+		}
+		v.outline = nil
+		return nil
+	}
+	return o
+}
+
+func (v *vimstate) closeOutline() error {
+	o := v.outline
+	v.outline = nil
+	if o.cancel != nil {
+		o.cancel()
+	}
+	v.ChannelExf("bwipeout %v", o.bufnr)
+	return nil
+}
+
+// documentSymbols returns the hierarchical document symbols for b, as
+// reported by server. It is safe to call from any goroutine
+func documentSymbols(ctx context.Context, server protocol.Server, b *types.Buffer) ([]protocol.DocumentSymbol, error) {
+	params := &protocol.DocumentSymbolParams{
+		TextDocument: b.ToTextDocumentIdentifier(),
+	}
+	res, err := server.DocumentSymbol(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("call to gopls.DocumentSymbol failed: %v", err)
+	}
+	// The result is either []SymbolInformation or []DocumentSymbol. We
+	// advertise hierarchical document symbol support, so we expect the
+	// latter; round trip via JSON to get at the concrete type.
+	byts, err := json.Marshal(res)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal document symbols: %v", err)
+	}
+	var syms []protocol.DocumentSymbol
+	if err := json.Unmarshal(byts, &syms); err != nil {
+		return nil, fmt.Errorf("failed to decode document symbols: %v", err)
+	}
+	return syms, nil
+}
+
+// updateOutline asynchronously re-queries the symbols for b, updating the
+// outline once the results are in. Any previous in-flight request is
+// cancelled, so that only the latest response is rendered.
+func (v *vimstate) updateOutline(b *types.Buffer) {
+	o := v.currentOutline()
+	if o == nil || o.srcBuf != b.Num {
+		return
+	}
+	if o.cancel != nil {
+		o.cancel()
+	}
+	server := v.server
+	ctx, cancel := context.WithCancel(context.Background())
+	o.cancel = cancel
+
+	v.tomb.Go(func() error {
+		syms, err := documentSymbols(ctx, server, b)
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+		if err != nil {
+			v.Logf("failed to update outline: %v", err)
+			return nil
+		}
+		v.Schedule(func(govim.Govim) error {
+			select {
+			case <-ctx.Done():
+				return nil
+			default:
+			}
+			if v.currentOutline() != o || o.srcBuf != b.Num {
+				return nil
+			}
+			o.cancel = nil
+			return v.renderOutline(o, b, syms)
+		})
+		return nil
+	})
+}
+
+// renderOutline updates the outline buffer to reflect syms. Only the lines
+// that have changed since the previous render are replaced (see
+// setScratchLines), so that an edit to a single function does not redraw the
+// entire tree.
+func (v *vimstate) renderOutline(o *outline, b *types.Buffer, syms []protocol.DocumentSymbol) error {
+	var entries []outlineEntry
+	var walk func(depth int, syms []protocol.DocumentSymbol)
+	walk = func(depth int, syms []protocol.DocumentSymbol) {
+		for _, s := range syms {
+			e := outlineEntry{
+				depth: depth,
+				sym:   s,
+			}
+			start, err1 := types.PointFromPosition(b, s.Range.Start)
+			end, err2 := types.PointFromPosition(b, s.Range.End)
+			if err1 == nil && err2 == nil {
+				e.rng = types.Range{Start: start, End: end}
+			}
+			entries = append(entries, e)
+			walk(depth+1, s.Children)
+		}
+	}
+	walk(0, syms)
+
+	lines := make([]string, len(entries))
+	for i, e := range entries {
+		lines[i] = fmt.Sprintf("%v%v %v", strings.Repeat("  ", e.depth), e.sym.Name, e.sym.Kind)
+	}
+
+	v.setScratchLines(o.bufnr, o.lines, lines)
+	o.entries = entries
+	o.lines = lines
+
+	var pos cursorPosition
+	v.Parse(v.ChannelExpr(cursorPositionExpr), &pos)
+	return v.outlineFollowCursor(&pos)
+}
+
+// outlineFollowCursor moves the cursor in the outline window(s) to the
+// innermost symbol that contains pos, if pos is in the outlined buffer
+func (v *vimstate) outlineFollowCursor(pos *cursorPosition) error {
+	o := v.outline
+	if o == nil || pos.BufNr != o.srcBuf {
+		return nil
+	}
+	b, ok := v.buffers[o.srcBuf]
+	if !ok {
+		return nil
+	}
+	p, err := types.PointFromVim(b, pos.Line, pos.Col)
+	if err != nil {
+		return nil
+	}
+	line := 0
+	for i, e := range o.entries {
+		// Children follow their parent, so the last match is the innermost
+		if e.rng != (types.Range{}) && p.IsWithin(e.rng) {
+			line = i + 1
+		}
+	}
+	if line == 0 {
+		return nil
+	}
+	var wins []int
+	v.Parse(v.ChannelCall("win_findbuf", o.bufnr), &wins)
+	for _, w := range wins {
+		v.ChannelCall("win_execute", w, fmt.Sprintf("call cursor(%v, 1)", line))
+	}
+	return nil
+}
+
+// outlineJump is called via the <CR> mapping in the outline buffer to jump
+// to the symbol on the given line of the outline
+func (v *vimstate) outlineJump(args ...json.RawMessage) (interface{}, error) {
+	o := v.currentOutline()
+	if o == nil {
+		return nil, fmt.Errorf("no outline is open")
+	}
+	line := v.ParseInt(args[0])
+	if line < 1 || line > len(o.entries) {
+		return nil, nil
+	}
+	b, ok := v.buffers[o.srcBuf]
+	if !ok {
+		return nil, fmt.Errorf("outlined buffer %v no longer exists", o.srcBuf)
+	}
+	e := o.entries[line-1]
+	var wins []int
+	v.Parse(v.ChannelCall("win_findbuf", b.Num), &wins)
+	if len(wins) > 0 {
+		v.ChannelCall("win_gotoid", wins[0])
+	} else {
+		v.ChannelEx("wincmd p")
+		v.ChannelExf("buffer %v", b.Num)
+	}
+	if cb, pos, err := v.cursorPos(); err == nil {
+		v.pushJumpStack(protocol.Location{
+			URI: protocol.DocumentURI(cb.URI()),
+			Range: protocol.Range{
+				Start: pos.ToPosition(),
+				End:   pos.ToPosition(),
+			},
+		})
+	}
+	p, err := types.PointFromPosition(b, e.sym.SelectionRange.Start)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve position of %v: %v", e.sym.Name, err)
+	}
+	v.ChannelEx("normal! m'")
+	v.ChannelCall("cursor", p.Line(), p.Col())
+	return nil, nil
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/govim/govim"
+	"github.com/govim/govim/cmd/govim/internal/golang_org_x_tools/lsp/protocol"
+	"github.com/kr/pretty"
+)
+
+// workDoneProgress is the state of a single gopls work done progress, as
+// reported via $/progress notifications
+type workDoneProgress struct {
+	// seq orders progress by the time at which it was created
+	seq int
+
+	title   string
+	message string
+
+	// percentage is nil if the progress does not report a percentage
+	percentage *float64
+}
+
+func (p *workDoneProgress) String() string {
+	var sb strings.Builder
+	sb.WriteString(p.title)
+	if p.message != "" {
+		if sb.Len() > 0 {
+			sb.WriteString(": ")
+		}
+		sb.WriteString(p.message)
+	}
+	if p.percentage != nil {
+		fmt.Fprintf(&sb, " (%v%%)", int(*p.percentage))
+	}
+	return sb.String()
+}
+
+// workDoneProgressValue is the union of protocol.WorkDoneProgressBegin,
+// protocol.WorkDoneProgressReport and protocol.WorkDoneProgressEnd
+type workDoneProgressValue struct {
+	Kind        string   `json:"kind"`
+	Title       string   `json:"title,omitempty"`
+	Message     string   `json:"message,omitempty"`
+	Percentage  *float64 `json:"percentage,omitempty"`
+	Cancellable bool     `json:"cancellable,omitempty"`
+}
+
+// progressKey returns the key for a progress token, which is either a number
+// or a string
+func progressKey(token protocol.ProgressToken) string {
+	return fmt.Sprint(token)
+}
+
+func (g *govimplugin) WorkDoneProgressCreate(ctxt context.Context, params *protocol.WorkDoneProgressCreateParams) error {
+	defer absorbShutdownErr()
+	g.logGoplsClientf("WorkDoneProgressCreate callback: %v", pretty.Sprint(params))
+	g.progressLock.Lock()
+	g.newProgress(progressKey(params.Token))
+	g.progressLock.Unlock()
+	return nil
+}
+
+// newProgress must be called with g.progressLock held
+func (g *govimplugin) newProgress(key string) *workDoneProgress {
+	p := &workDoneProgress{
+		seq: g.progressSeq,
+	}
+	g.progressSeq++
+	g.progress[key] = p
+	return p
+}
+
+func (g *govimplugin) Progress(ctxt context.Context, params *protocol.ProgressParams) error {
+	defer absorbShutdownErr()
+	// The value is only typed as interface{}, hence round trip via JSON
+	var val workDoneProgressValue
+	byts, err := json.Marshal(params.Value)
+	if err == nil {
+		err = json.Unmarshal(byts, &val)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to decode progress value %v: %v", pretty.Sprint(params.Value), err)
+	}
+	g.logGoplsClientf("Progress callback: token %v: %v", progressKey(params.Token), pretty.Sprint(val))
+
+	key := progressKey(params.Token)
+	g.progressLock.Lock()
+	p, ok := g.progress[key]
+	switch val.Kind {
+	case "begin":
+		// gopls may use a token supplied by the client in a request, in which
+		// case there will have been no WorkDoneProgressCreate call
+		if !ok {
+			p = g.newProgress(key)
+		}
+		p.title = val.Title
+		p.message = val.Message
+		p.percentage = val.Percentage
+	case "report":
+		if ok {
+			if val.Message != "" {
+				p.message = val.Message
+			}
+			if val.Percentage != nil {
+				p.percentage = val.Percentage
+			}
+		}
+	case "end":
+		delete(g.progress, key)
+	}
+	g.progressLock.Unlock()
+
+	g.Schedule(func(govim.Govim) error {
+		return g.vimstate.progressChanged()
+	})
+	return nil
+}
+
+// activeProgress returns the description of each in-progress piece of work,
+// oldest first
+func (g *govimplugin) activeProgress() []string {
+	g.progressLock.Lock()
+	defer g.progressLock.Unlock()
+	var ps []*workDoneProgress
+	for _, p := range g.progress {
+		// Progress that has been created but not begun has nothing to show
+		if p.title == "" && p.message == "" {
+			continue
+		}
+		ps = append(ps, p)
+	}
+	sort.Slice(ps, func(i, j int) bool {
+		return ps[i].seq < ps[j].seq
+	})
+	res := make([]string, len(ps))
+	for i, p := range ps {
+		res[i] = p.String()
+	}
+	return res
+}
+
+// progressString is the implementation of config.FunctionProgress. It
+// returns a short description of the current gopls progress, suitable for
+// use in a statusline, or the empty string if gopls is not busy
+func (v *vimstate) progressString(args ...json.RawMessage) (interface{}, error) {
+	active := v.activeProgress()
+	switch len(active) {
+	case 0:
+		return "", nil
+	case 1:
+		return active[0], nil
+	}
+	return fmt.Sprintf("%v (+%v more)", active[len(active)-1], len(active)-1), nil
+}
+
+// progressChanged updates the progress popup, if enabled, and redraws the
+// statusline(s) in case they show progress via config.FunctionProgress
+func (v *vimstate) progressChanged() error {
+	defer v.ChannelEx("redrawstatus!")
+	active := v.activeProgress()
+	if len(active) == 0 || v.config.ProgressPopup == nil || !*v.config.ProgressPopup {
+		if v.progressPopupID > 0 {
+			v.ChannelCall("popup_close", v.progressPopupID)
+			v.progressPopupID = 0
+		}
+		return nil
+	}
+	if v.progressPopupID > 0 {
+		v.ChannelCall("popup_settext", v.progressPopupID, active)
+		return nil
+	}
+	opts := map[string]interface{}{
+		"pos":       "topright",
+		"line":      1,
+		"col":       v.ParseInt(v.ChannelExpr("&columns")),
+		"padding":   []int{0, 1, 0, 1},
+		"wrap":      false,
+		"highlight": "Pmenu",
+	}
+	v.progressPopupID = v.ParseInt(v.ChannelCall("popup_create", active, opts))
+	return nil
+}
@@ -23,6 +23,9 @@ func (q quickfixEntry) equalModuloBuffer(q2 quickfixEntry) bool {
 }
 
 func (v *vimstate) quickfixDiagnostics(flags govim.CommandFlags, args ...string) error {
+	if v.diagnosticsInLocationLists() {
+		return v.updateLocationListsWithDiagnostics(true)
+	}
 	wasNotDiagnostics := !v.quickfixIsDiagnostics
 	v.quickfixIsDiagnostics = true
 	return v.updateQuickfixWithDiagnostics(true, wasNotDiagnostics)
@@ -37,13 +40,16 @@ func (v *vimstate) updateQuickfixWithDiagnostics(force bool, wasPrevNotDiagnosti
 	if !force && (v.config.QuickfixAutoDiagnostics == nil || !*v.config.QuickfixAutoDiagnostics) {
 		return nil
 	}
+	if v.diagnosticsInLocationLists() {
+		return v.updateLocationListsWithDiagnostics(force)
+	}
 	diagsRef := v.diagnostics()
 	work := v.lastDiagnosticsQuickfix != diagsRef
 	v.lastDiagnosticsQuickfix = diagsRef
 	if (!force && !work) || !v.quickfixIsDiagnostics {
 		return nil
 	}
-	diags := *diagsRef
+	diags := v.filterDiagnostics(*diagsRef, v.config.DiagnosticsQuickfixSeverity)
 
 	// must be non-nil
 	fixes := []quickfixEntry{}
@@ -2,25 +2,89 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"math"
+	"path/filepath"
 	"sort"
 	"strings"
 
 	"github.com/govim/govim"
+	"github.com/govim/govim/cmd/govim/config"
+	"github.com/govim/govim/cmd/govim/internal/golang_org_x_tools/lsp/diff"
+	"github.com/govim/govim/cmd/govim/internal/golang_org_x_tools/lsp/diff/myers"
 	"github.com/govim/govim/cmd/govim/internal/golang_org_x_tools/lsp/protocol"
+	"github.com/govim/govim/cmd/govim/internal/golang_org_x_tools/span"
+	"github.com/govim/govim/cmd/govim/internal/types"
 )
 
+const renamePreviewBufName = "govim-rename-preview"
+
+// renamePreview is the state of the rename preview window (see
+// config.Config.RenamePreview)
+type renamePreview struct {
+	bufnr int
+
+	// winid is the window from which the rename was started, and to which
+	// we return to apply the accepted changes
+	winid int
+	mods  govim.CommModList
+
+	files []*renamePreviewFile
+
+	// lines are the current contents of the preview buffer
+	lines []string
+}
+
+// renamePreviewFile is a file affected by the rename being previewed
+type renamePreviewFile struct {
+	change   protocol.TextDocumentEdit
+	accepted bool
+
+	// name is the path of the file relative to the working directory, if
+	// possible
+	name string
+
+	// diff is the unified diff of the change
+	diff []string
+
+	// line is the (1-indexed) line on which the file's entry starts in the
+	// preview buffer. The entry spans 1+len(diff) lines
+	line int
+}
+
 func (v *vimstate) rename(flags govim.CommandFlags, args ...string) error {
 	b, pos, err := v.cursorPos()
 	if err != nil {
 		return fmt.Errorf("failed to get current position: %v", err)
 	}
+	prep, err := v.server.PrepareRename(context.Background(), &protocol.PrepareRenameParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: b.ToTextDocumentIdentifier(),
+			Position:     pos.ToPosition(),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("call to gopls.PrepareRename failed: %v", err)
+	}
+	if prep == nil {
+		return fmt.Errorf("cannot rename the identifier under the cursor")
+	}
+	start, err := types.PointFromPosition(b, prep.Start)
+	if err != nil {
+		return fmt.Errorf("failed to derive start of rename range: %v", err)
+	}
+	end, err := types.PointFromPosition(b, prep.End)
+	if err != nil {
+		return fmt.Errorf("failed to derive end of rename range: %v", err)
+	}
+	curr := string(b.Contents()[start.Offset():end.Offset()])
+
 	var renameTo string
 	if len(args) == 1 {
 		renameTo = args[0]
 	} else {
-		curr := v.ParseString(v.ChannelExprf(`expand("<cword>")`))
 		renameTo = v.ParseString(v.ChannelExprf(`input("govim: rename '%v' to: ", %q)`, curr, curr))
 	}
 	params := &protocol.RenameParams{
@@ -36,20 +100,220 @@ func (v *vimstate) rename(flags govim.CommandFlags, args ...string) error {
 		return fmt.Errorf("called to gopls.Rename failed: %v", err)
 	}
 
+	if v.config.RenamePreview != nil && *v.config.RenamePreview {
+		return v.previewRename(flags.Mods, curr, renameTo, res.DocumentChanges)
+	}
 	return v.applyMultiBufTextedits(flags.Mods, res.DocumentChanges)
 }
 
+// previewRename opens the rename preview window, showing a unified diff of
+// the change to each file in changes. mods control where the preview window
+// is opened, and are used when the accepted changes are applied via
+// applyMultiBufTextedits.
+func (v *vimstate) previewRename(mods govim.CommModList, from, to string, changes []protocol.TextDocumentEdit) error {
+	rp := &renamePreview{
+		winid: v.ParseInt(v.ChannelCall("win_getid")),
+		mods:  mods,
+	}
+	for _, c := range changes {
+		f, err := v.newRenamePreviewFile(c)
+		if err != nil {
+			return err
+		}
+		if len(f.diff) > 0 {
+			rp.files = append(rp.files, f)
+		}
+	}
+	if len(rp.files) == 0 {
+		v.Logf("No changes to apply")
+		return nil
+	}
+	sort.Slice(rp.files, func(i, j int) bool {
+		return rp.files[i].name < rp.files[j].name
+	})
+
+	if old := v.renamePreview; old != nil && v.ParseInt(v.ChannelCall("bufexists", old.bufnr)) == 1 {
+		v.ChannelExf("bwipeout %v", old.bufnr)
+	}
+	winMods := mods.String()
+	if winMods == "" {
+		winMods = "botright"
+	}
+	rp.bufnr = v.openScratchWindow(winMods, renamePreviewBufName, "diff",
+		fmt.Sprintf("nnoremap <buffer> <silent> a :call %v%v(line('.'), 1)<CR>", PluginPrefix, config.FunctionRenamePreviewMark),
+		fmt.Sprintf("nnoremap <buffer> <silent> r :call %v%v(line('.'), 0)<CR>", PluginPrefix, config.FunctionRenamePreviewMark),
+		fmt.Sprintf("nnoremap <buffer> <silent> A :call %v%v()<CR>", PluginPrefix, config.FunctionRenamePreviewApply),
+		"nnoremap <buffer> <silent> q :close<CR>",
+	)
+	v.renamePreview = rp
+
+	rp.lines = []string{
+		fmt.Sprintf("Rename %v to %v", from, to),
+		"a: accept file, r: reject file, A: apply accepted changes, q: cancel",
+	}
+	for _, f := range rp.files {
+		f.accepted = true
+	}
+	v.renderRenamePreview(rp)
+	v.ChannelCall("win_gotoid", v.ParseInt(v.ChannelCall("bufwinid", rp.bufnr)))
+	return nil
+}
+
+// newRenamePreviewFile computes the unified diff of the change c
+func (v *vimstate) newRenamePreviewFile(c protocol.TextDocumentEdit) (*renamePreviewFile, error) {
+	uri := span.URI(c.TextDocument.URI)
+	fn := uri.Filename()
+	var b *types.Buffer
+	for _, vb := range v.buffers {
+		if vb.Loaded && vb.URI() == uri {
+			b = vb
+		}
+	}
+	if b == nil {
+		byts, err := ioutil.ReadFile(fn)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read contents of %v: %v", fn, err)
+		}
+		b = types.NewBuffer(-1, fn, byts, false)
+	}
+	before := string(b.Contents())
+	after, err := applyTextEditsToContents(b, c.Edits)
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply edits to %v: %v", fn, err)
+	}
+	name := fn
+	if rel, err := filepath.Rel(v.workingDirectory, fn); err == nil {
+		name = rel
+	}
+	f := &renamePreviewFile{
+		change: c,
+		name:   name,
+	}
+	u := diff.ToUnified("a/"+name, "b/"+name, before, myers.ComputeEdits(uri, before, after))
+	if d := fmt.Sprint(u); d != "" {
+		f.diff = strings.Split(strings.TrimSuffix(d, "\n"), "\n")
+	}
+	return f, nil
+}
+
+// applyTextEditsToContents returns the contents of b with edits applied,
+// without changing b itself
+func applyTextEditsToContents(b *types.Buffer, edits []protocol.TextEdit) (string, error) {
+	type offsetEdit struct {
+		start, end int
+		text       string
+	}
+	var oes []offsetEdit
+	for _, e := range edits {
+		start, err := types.PointFromPosition(b, e.Range.Start)
+		if err != nil {
+			return "", fmt.Errorf("failed to derive start point from position: %v", err)
+		}
+		end, err := types.PointFromPosition(b, e.Range.End)
+		if err != nil {
+			return "", fmt.Errorf("failed to derive end point from position: %v", err)
+		}
+		oes = append(oes, offsetEdit{start.Offset(), end.Offset(), e.NewText})
+	}
+	sort.SliceStable(oes, func(i, j int) bool {
+		return oes[i].start < oes[j].start
+	})
+	contents := b.Contents()
+	var sb strings.Builder
+	last := 0
+	for _, e := range oes {
+		if e.start < last {
+			return "", fmt.Errorf("overlapping edits")
+		}
+		sb.Write(contents[last:e.start])
+		sb.WriteString(e.text)
+		last = e.end
+	}
+	sb.Write(contents[last:])
+	return sb.String(), nil
+}
+
+// renderRenamePreview updates the rename preview buffer to reflect the
+// current state of rp. The first two lines (the title and help) are left
+// unchanged.
+func (v *vimstate) renderRenamePreview(rp *renamePreview) {
+	lines := append([]string{}, rp.lines[:2]...)
+	for _, f := range rp.files {
+		mark := "[ ]"
+		if f.accepted {
+			mark = "[x]"
+		}
+		lines = append(lines, "")
+		f.line = len(lines) + 1
+		lines = append(lines, mark+" "+f.name)
+		lines = append(lines, f.diff...)
+	}
+	v.setScratchLines(rp.bufnr, rp.lines, lines)
+	rp.lines = lines
+}
+
+// currentRenamePreview returns the rename preview state if the cursor is in
+// the rename preview window, else an error
+func (v *vimstate) currentRenamePreview() (*renamePreview, error) {
+	rp := v.renamePreview
+	if rp == nil || v.ParseInt(v.ChannelExpr(`bufnr("")`)) != rp.bufnr {
+		return nil, fmt.Errorf("not in a rename preview window")
+	}
+	return rp, nil
+}
+
+// renamePreviewMark accepts (accept is 1) or rejects (accept is 0) the
+// changes to the file on the given line of the rename preview window
+func (v *vimstate) renamePreviewMark(args ...json.RawMessage) (interface{}, error) {
+	rp, err := v.currentRenamePreview()
+	if err != nil {
+		return nil, err
+	}
+	line := v.ParseInt(args[0])
+	accept := v.ParseInt(args[1]) == 1
+	for _, f := range rp.files {
+		if line >= f.line && line <= f.line+len(f.diff) {
+			f.accepted = accept
+			v.renderRenamePreview(rp)
+			break
+		}
+	}
+	return nil, nil
+}
+
+// renamePreviewApply closes the rename preview window and applies the
+// accepted changes
+func (v *vimstate) renamePreviewApply(args ...json.RawMessage) (interface{}, error) {
+	rp, err := v.currentRenamePreview()
+	if err != nil {
+		return nil, err
+	}
+	v.renamePreview = nil
+	var changes []protocol.TextDocumentEdit
+	for _, f := range rp.files {
+		if f.accepted {
+			changes = append(changes, f.change)
+		}
+	}
+	v.ChannelExf("bwipeout %v", rp.bufnr)
+	v.ChannelCall("win_gotoid", rp.winid)
+	return nil, v.applyMultiBufTextedits(rp.mods, changes)
+}
+
 func (v *vimstate) applyMultiBufTextedits(splitMods govim.CommModList, changes []protocol.TextDocumentEdit) error {
 	allChanges := changes
 	if len(allChanges) == 0 {
-		v.Logf("No changes to apply for rename")
+		v.Logf("No changes to apply")
 		return nil
 	}
-	// TODO: it feels like we need a new config variable for the strategy to use
-	// when making edits of this sort (to multiple files). It doesn't feel right
-	// to use the value of &switchbuf because there might be multiple changes
-	// (as opposed to jumping to a single definition/location). For now we
-	// hardcode a split.
+	defer v.startEditTransaction()()
+	// We deliberately don't use &switchbuf here, because there might be
+	// multiple changes (as opposed to jumping to a single
+	// definition/location)
+	strategy := config.MultiFileEditStrategySplit
+	if v.config.MultiFileEditStrategy != nil {
+		strategy = *v.config.MultiFileEditStrategy
+	}
 	vp := v.Viewport()
 	bufNrs := make(map[string]int)
 	var fps []string
@@ -65,6 +329,7 @@ func (v *vimstate) applyMultiBufTextedits(splitMods govim.CommModList, changes [
 		tf := strings.TrimPrefix(filepath, "file://")
 		var bufinfo []struct {
 			BufNr   int   `json:"bufnr"`
+			Loaded  int   `json:"loaded"`
 			Windows []int `json:"windows"`
 		}
 		v.Parse(v.ChannelCall("getbufinfo", tf), &bufinfo)
@@ -75,15 +340,29 @@ func (v *vimstate) applyMultiBufTextedits(splitMods govim.CommModList, changes [
 			if len(bufinfo[0].Windows) > 0 {
 				continue
 			}
+			if strategy == config.MultiFileEditStrategyBufLoad && bufinfo[0].Loaded == 1 {
+				continue
+			}
 		default:
 			return fmt.Errorf("got back multiple buffers searching for %v", tf)
 		}
-		// Hard code split for now
-		v.ChannelExf("%v split %v", splitMods, tf)
+		switch strategy {
+		case config.MultiFileEditStrategyBufLoad:
+			bufnr := v.ParseInt(v.ChannelCall("bufadd", tf))
+			v.ChannelCall("setbufvar", bufnr, "&buflisted", 1)
+			v.ChannelCall("bufload", bufnr)
+		case config.MultiFileEditStrategyTab:
+			v.ChannelExf("%v tabnew %v", splitMods, tf)
+		default:
+			v.ChannelExf("%v split %v", splitMods, tf)
+		}
 		bufNrs[filepath] = v.ParseInt(v.ChannelCall("bufnr", tf))
 	}
 	v.ChannelCall("win_gotoid", vp.Current.WinID)
 
+	// Verify all buffer versions before applying any edits so that we don't
+	// leave things half-applied
+	bufs := make(map[string]*types.Buffer)
 	for _, filepath := range fps {
 		changes := uriMap[protocol.DocumentURI(filepath)]
 		if len(changes.Edits) == 0 {
@@ -101,9 +380,20 @@ func (v *vimstate) applyMultiBufTextedits(splitMods govim.CommModList, changes [
 		if ev > 0 && ev != b.Version {
 			return fmt.Errorf("edit for buffer %v (%v) was for version %v, current version is %v", tf, bufnr, ev, b.Version)
 		}
+		bufs[filepath] = b
+	}
+
+	for _, filepath := range fps {
+		b, ok := bufs[filepath]
+		if !ok {
+			continue
+		}
+		changes := uriMap[protocol.DocumentURI(filepath)]
+		before := b.Contents()
 		if err := v.applyProtocolTextEdits(b, changes.Edits); err != nil {
-			return fmt.Errorf("failed to apply edits for %v: %v", tf, err)
+			return fmt.Errorf("failed to apply edits for %v: %v", strings.TrimPrefix(filepath, "file://"), err)
 		}
+		v.editTransaction.record(b, before)
 	}
 	return nil
 }
@@ -0,0 +1,59 @@
+package main
+
+// openScratchWindow opens a new window onto a scratch buffer with the given
+// name and filetype, returning the buffer number. mods are the command
+// modifiers used to open the window; the default is :vertical botright. cmds
+// are then executed in the new window, e.g. to define buffer-local mappings,
+// before the cursor is returned to the previous window.
+//
+// The scratch buffer is wiped when its window is closed.
+func (v *vimstate) openScratchWindow(mods string, name, filetype string, cmds ...string) int {
+	if mods == "" {
+		mods = "vertical botright"
+	}
+	v.ChannelExf("%v 40new", mods)
+	v.ChannelEx("setlocal buftype=nofile bufhidden=wipe noswapfile nobuflisted nomodifiable nowrap nonumber cursorline winfixwidth")
+	v.ChannelExf("silent file %v", name)
+	v.ChannelExf("setlocal filetype=%v", filetype)
+	for _, c := range cmds {
+		v.ChannelEx(c)
+	}
+	bufnr := v.ParseInt(v.ChannelExpr(`bufnr("")`))
+	v.ChannelEx("wincmd p")
+	return bufnr
+}
+
+// setScratchLines updates the contents of the scratch buffer bufnr from old
+// to lines. Only the lines that differ are replaced, which avoids redrawing
+// (and losing the cursor position in) the entire buffer for a small change.
+func (v *vimstate) setScratchLines(bufnr int, old, lines []string) {
+	pre := 0
+	for pre < len(old) && pre < len(lines) && old[pre] == lines[pre] {
+		pre++
+	}
+	suf := 0
+	for suf < len(old)-pre && suf < len(lines)-pre && old[len(old)-1-suf] == lines[len(lines)-1-suf] {
+		suf++
+	}
+	if pre == len(old) && pre == len(lines) {
+		return
+	}
+	v.BatchStart()
+	defer v.BatchCancelIfNotEnded()
+	v.BatchChannelCall("setbufvar", bufnr, "&modifiable", 1)
+	if pre == 0 && suf == 0 {
+		// Deleting all lines leaves a single empty line, which setbufline
+		// then overwrites
+		v.BatchAssertChannelCall(AssertIsZero(), "deletebufline", bufnr, 1, "$")
+		v.BatchAssertChannelCall(AssertIsZero(), "setbufline", bufnr, 1, lines)
+	} else {
+		if last := len(old) - suf; last > pre {
+			v.BatchAssertChannelCall(AssertIsZero(), "deletebufline", bufnr, pre+1, last)
+		}
+		if added := lines[pre : len(lines)-suf]; len(added) > 0 {
+			v.BatchAssertChannelCall(AssertIsZero(), "appendbufline", bufnr, pre, added)
+		}
+	}
+	v.BatchChannelCall("setbufvar", bufnr, "&modifiable", 0)
+	v.MustBatchEnd()
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"go/ast"
+	"go/token"
+	"unicode/utf8"
+
+	"github.com/govim/govim/cmd/govim/internal/golang_org_x_tools/lsp/protocol"
+	"github.com/govim/govim/cmd/govim/internal/types"
+)
+
+// selectionRanges is the stack of nested ranges that enclose the position at
+// which FunctionExpandSelection was first called, innermost first
+type selectionRanges struct {
+	// version is the buffer version for which the ranges were computed
+	version int
+
+	// ranges are the enclosing ranges, innermost first
+	ranges []selectionRange
+
+	// current is the index in ranges of the range currently selected
+	current int
+}
+
+// selectionRange is a range of byte offsets [start, end) within a buffer
+type selectionRange struct {
+	start int
+	end   int
+}
+
+func (v *vimstate) expandSelection(args ...json.RawMessage) (interface{}, error) {
+	b, pos, err := v.cursorPos()
+	if err != nil {
+		return nil, err
+	}
+	sr := v.currentSelectionRanges(b)
+	if sr == nil {
+		ranges := v.enclosingRanges(b, pos)
+		if len(ranges) == 0 {
+			return nil, nil
+		}
+		sr = &selectionRanges{
+			version: b.Version,
+			ranges:  ranges,
+			current: -1,
+		}
+		v.selectionRanges[b.Num] = sr
+	}
+	if sr.current < len(sr.ranges)-1 {
+		sr.current++
+	}
+	return nil, v.selectRange(b, sr.ranges[sr.current])
+}
+
+func (v *vimstate) shrinkSelection(args ...json.RawMessage) (interface{}, error) {
+	b, _, err := v.cursorPos()
+	if err != nil {
+		return nil, err
+	}
+	sr := v.currentSelectionRanges(b)
+	if sr == nil {
+		return nil, nil
+	}
+	if sr.current > 0 {
+		sr.current--
+	}
+	return nil, v.selectRange(b, sr.ranges[sr.current])
+}
+
+// currentSelectionRanges returns the selection ranges for b if the last
+// visual selection is the range we last selected, i.e. the user is walking
+// up or down the ranges. Otherwise it returns nil.
+func (v *vimstate) currentSelectionRanges(b *types.Buffer) *selectionRanges {
+	sr, ok := v.selectionRanges[b.Num]
+	if !ok || sr.version != b.Version || sr.current < 0 {
+		return nil
+	}
+	var marks [2][]int
+	v.Parse(v.ChannelExpr(`[getpos("'<"), getpos("'>")]`), &marks)
+	start, end, err := v.rangePoints(b, sr.ranges[sr.current])
+	if err != nil {
+		return nil
+	}
+	for i, p := range []types.Point{start, end} {
+		if len(marks[i]) < 3 || marks[i][1] != p.Line() || marks[i][2] != p.Col() {
+			return nil
+		}
+	}
+	return sr
+}
+
+// selectRange visually selects r in the current window, leaving the cursor
+// at the end of the selection
+func (v *vimstate) selectRange(b *types.Buffer, r selectionRange) error {
+	return v.visualSelect(b, r, "v")
+}
+
+// visualSelect visually selects r in the current window using the visual
+// mode mode, i.e. "v" or "V", leaving the cursor at the end of the selection
+func (v *vimstate) visualSelect(b *types.Buffer, r selectionRange, mode string) error {
+	start, end, err := v.rangePoints(b, r)
+	if err != nil {
+		return err
+	}
+	v.ChannelCall("cursor", start.Line(), start.Col())
+	v.ChannelEx("normal! " + mode)
+	v.ChannelCall("cursor", end.Line(), end.Col())
+	return nil
+}
+
+// rangePoints returns the first and last (as opposed to one beyond the last)
+// points of r, i.e. those which Vim uses to mark a visual selection
+func (v *vimstate) rangePoints(b *types.Buffer, r selectionRange) (start, end types.Point, err error) {
+	start, err = types.PointFromOffset(b, r.start)
+	if err != nil {
+		return
+	}
+	// The last point is the start of the last rune in r, which may be more
+	// than one byte before its end
+	last := r.start
+	if r.end > r.start {
+		_, size := utf8.DecodeLastRune(b.Contents()[:r.end])
+		last = r.end - size
+	}
+	end, err = types.PointFromOffset(b, last)
+	return
+}
+
+// enclosingRanges returns the syntactic ranges that enclose pos, innermost
+// first. The ranges are provided by gopls where possible; otherwise they are
+// derived from the buffer's AST.
+func (v *vimstate) enclosingRanges(b *types.Buffer, pos types.Point) []selectionRange {
+	var res []selectionRange
+	add := func(r selectionRange) {
+		if r.start >= r.end {
+			return
+		}
+		if l := len(res); l > 0 && res[l-1] == r {
+			return
+		}
+		res = append(res, r)
+	}
+	srs, err := v.server.SelectionRange(context.Background(), &protocol.SelectionRangeParams{
+		TextDocument: b.ToTextDocumentIdentifier(),
+		Positions:    []protocol.Position{pos.ToPosition()},
+	})
+	if err != nil {
+		v.Logf("selectionRange call failed; falling back to AST: %v", err)
+	}
+	if len(srs) > 0 {
+		for sr := &srs[0]; sr != nil; sr = sr.Parent {
+			start, err := types.PointFromPosition(b, sr.Range.Start)
+			if err != nil {
+				break
+			}
+			end, err := types.PointFromPosition(b, sr.Range.End)
+			if err != nil {
+				break
+			}
+			add(selectionRange{start: start.Offset(), end: end.Offset()})
+		}
+		if len(res) > 0 {
+			return res
+		}
+	}
+
+	// Ensure we block for the result of any in-flight parse
+	if b.ASTWait == nil {
+		return nil
+	}
+	<-b.ASTWait
+	if b.AST == nil {
+		return nil
+	}
+	tf := b.Fset.File(b.AST.Pos())
+	if tf == nil {
+		return nil
+	}
+	// Collect the nodes that enclose pos, outermost first
+	var path []selectionRange
+	ast.Inspect(b.AST, func(n ast.Node) bool {
+		if n == nil || !n.Pos().IsValid() || !n.End().IsValid() {
+			return false
+		}
+		start, end := tf.Offset(n.Pos()), offsetOrFileSize(tf, n.End())
+		if pos.Offset() < start || pos.Offset() >= end {
+			return false
+		}
+		path = append(path, selectionRange{start: start, end: end})
+		return true
+	})
+	for i := len(path) - 1; i >= 0; i-- {
+		add(path[i])
+	}
+	return res
+}
+
+// offsetOrFileSize returns the offset of pos in tf, working around
+// https://github.com/golang/go/issues/33649 where the end of a node can be
+// beyond the end of the file
+func offsetOrFileSize(tf *token.File, pos token.Pos) int {
+	if int(pos) > tf.Base()+tf.Size() {
+		return tf.Size()
+	}
+	return tf.Offset(pos)
+}
@@ -0,0 +1,549 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/govim/govim"
+	"github.com/govim/govim/cmd/govim/config"
+	"github.com/govim/govim/cmd/govim/internal/golang_org_x_tools/jsonrpc2"
+	"github.com/govim/govim/cmd/govim/internal/golang_org_x_tools/lsp/protocol"
+	"github.com/govim/govim/cmd/govim/internal/types"
+)
+
+// semanticTokensCapabilities are the semantic tokens capabilities that
+// gopls declares in its initialize result
+type semanticTokensCapabilities struct {
+	// legend gives the token types and modifiers indexed by the encoded
+	// semantic tokens
+	legend semanticTokensLegend
+
+	// supportsRange is set if gopls supports requesting the tokens of a
+	// range, in which case only the tokens of visible lines are requested
+	supportsRange bool
+
+	// supportsEdits is set if gopls supports requesting the edits to the
+	// previous tokens of a document
+	supportsEdits bool
+}
+
+type semanticTokensLegend struct {
+	TokenTypes     []string `json:"tokenTypes"`
+	TokenModifiers []string `json:"tokenModifiers"`
+}
+
+// parseSemanticTokensCapabilities decodes the semantic tokens capabilities
+// from the raw initialize result res. The protocol package does not (yet)
+// model them. It returns nil if gopls does not support semantic tokens.
+func parseSemanticTokensCapabilities(res json.RawMessage) (*semanticTokensCapabilities, error) {
+	var init struct {
+		Capabilities struct {
+			SemanticTokensProvider *struct {
+				Legend semanticTokensLegend `json:"legend"`
+				// RangeProvider is either a boolean or an empty object
+				RangeProvider json.RawMessage `json:"rangeProvider"`
+				// DocumentProvider is either a boolean or an object
+				DocumentProvider json.RawMessage `json:"documentProvider"`
+			} `json:"semanticTokensProvider"`
+		} `json:"capabilities"`
+	}
+	if err := json.Unmarshal(res, &init); err != nil {
+		return nil, err
+	}
+	p := init.Capabilities.SemanticTokensProvider
+	if p == nil || len(p.Legend.TokenTypes) == 0 {
+		return nil, nil
+	}
+	caps := &semanticTokensCapabilities{
+		legend:        p.Legend,
+		supportsRange: len(p.RangeProvider) > 0 && string(p.RangeProvider) != "false" && string(p.RangeProvider) != "null",
+	}
+	var doc struct {
+		Edits bool `json:"edits"`
+	}
+	if json.Unmarshal(p.DocumentProvider, &doc) == nil {
+		caps.supportsEdits = doc.Edits
+	}
+	return caps, nil
+}
+
+// semanticTokenTypes maps the token types we highlight to their highlight
+// group. Keywords, comments, literals and the like are left to the regular
+// syntax highlighting.
+var semanticTokenTypes = map[string]config.Highlight{
+	"namespace":     config.HighlightSemNamespace,
+	"type":          config.HighlightSemType,
+	"class":         config.HighlightSemType,
+	"enum":          config.HighlightSemType,
+	"interface":     config.HighlightSemType,
+	"struct":        config.HighlightSemType,
+	"typeParameter": config.HighlightSemType,
+	"parameter":     config.HighlightSemParameter,
+	"variable":      config.HighlightSemVariable,
+	"property":      config.HighlightSemProperty,
+	"enumMember":    config.HighlightSemProperty,
+	"function":      config.HighlightSemFunction,
+	"macro":         config.HighlightSemFunction,
+	"method":        config.HighlightSemMethod,
+	// Earlier versions of gopls use member in place of method
+	"member": config.HighlightSemMethod,
+}
+
+// semanticTokenModifiers maps the token modifiers we highlight to their
+// highlight group
+var semanticTokenModifiers = map[string]config.Highlight{
+	"readonly":       config.HighlightSemReadonly,
+	"defaultLibrary": config.HighlightSemDefaultLibrary,
+	"deprecated":     config.HighlightSemDeprecated,
+}
+
+// semanticTokenTypeHighlights and semanticTokenModifierHighlights are the
+// (distinct) values of semanticTokenTypes and semanticTokenModifiers
+// respectively, for which text property types are defined
+var (
+	semanticTokenTypeHighlights = []config.Highlight{
+		config.HighlightSemNamespace,
+		config.HighlightSemType,
+		config.HighlightSemParameter,
+		config.HighlightSemVariable,
+		config.HighlightSemProperty,
+		config.HighlightSemFunction,
+		config.HighlightSemMethod,
+	}
+	semanticTokenModifierHighlights = []config.Highlight{
+		config.HighlightSemReadonly,
+		config.HighlightSemDefaultLibrary,
+		config.HighlightSemDeprecated,
+	}
+)
+
+// bufSemanticTokens are the semantic tokens for a buffer
+type bufSemanticTokens struct {
+	// version is the buffer version for which tokens were fetched
+	version int
+
+	// resultID identifies data to gopls, such that subsequent requests
+	// can be for the edits to data rather than all tokens. Only used when
+	// all the tokens of a buffer are requested
+	resultID string
+
+	// data is the encoded form of the tokens as returned by gopls. Only
+	// used when all the tokens of a buffer are requested
+	data []float64
+
+	// tokens are the decoded tokens that we highlight, keyed by (1-indexed)
+	// line number
+	tokens map[int][]semanticToken
+
+	// fetched is the set of lines for which tokens have been fetched. It is
+	// nil if all the tokens of the buffer have been fetched
+	fetched map[int]bool
+
+	// rendered is the set of lines for which tokens have been highlighted
+	rendered map[int]bool
+
+	// cancel cancels the in-flight request for the buffer, if any. It must
+	// only be used on the Vim "thread"
+	cancel context.CancelFunc
+}
+
+// semanticToken is a decoded semantic token, with Vim (1-indexed, byte)
+// positions
+type semanticToken struct {
+	line   int
+	col    int
+	endCol int
+	hi     config.Highlight
+	mods   []config.Highlight
+}
+
+func (v *vimstate) semanticHighlightingEnabled() bool {
+	return v.config.SemanticHighlighting != nil && *v.config.SemanticHighlighting
+}
+
+// updateSemanticHighlighting fetches the semantic tokens of all visible
+// buffers whose tokens are out of date or (if gopls supports requesting the
+// tokens of a range) not yet fetched for the visible lines, and highlights
+// any lines visible in a window that are not yet highlighted. Like
+// updateCodeLenses it is called when the user stops being busy and when a
+// buffer changes.
+func (v *vimstate) updateSemanticHighlighting() error {
+	if !v.semanticHighlightingEnabled() || v.semanticTokensUnsupported || v.userBusy {
+		return nil
+	}
+	if v.server != nil && v.semanticTokensCaps == nil {
+		v.Logf("gopls does not support semantic tokens; disabling semantic highlighting")
+		v.semanticTokensUnsupported = true
+		return nil
+	}
+	vp := v.Viewport()
+	seen := make(map[int]bool)
+	for _, w := range vp.Windows {
+		b, ok := v.buffers[w.BufNr]
+		if !ok || seen[b.Num] {
+			continue
+		}
+		seen[b.Num] = true
+		st := v.semanticTokens[b.Num]
+		if st == nil {
+			st = &bufSemanticTokens{version: -1}
+			v.semanticTokens[b.Num] = st
+		}
+		if st.cancel != nil {
+			// The in-flight request renders its result, and
+			// updateSemanticHighlighting will be called again
+			continue
+		}
+		if v.semanticTokensCaps != nil && v.semanticTokensCaps.supportsRange {
+			if start, end, ok := unfetchedSemanticTokensLines(b, st, vp); ok {
+				v.fetchSemanticTokensRange(b, st, start, end)
+				continue
+			}
+		} else if st.version != b.Version {
+			v.fetchSemanticTokens(b, st)
+			continue
+		}
+		if err := v.renderSemanticTokens(b, st, vp); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unfetchedSemanticTokensLines returns the first and last (1-indexed) lines
+// of b visible in a window in vp for which tokens have not been fetched for
+// the current version of b. ok is false if there are no such lines.
+func unfetchedSemanticTokensLines(b *types.Buffer, st *bufSemanticTokens, vp govim.Viewport) (start, end int, ok bool) {
+	for _, w := range vp.Windows {
+		if w.BufNr != b.Num {
+			continue
+		}
+		for l := w.TopLine; l <= w.BotLine; l++ {
+			if st.version == b.Version && st.fetched[l] {
+				continue
+			}
+			if !ok || l < start {
+				start = l
+			}
+			if !ok || l > end {
+				end = l
+			}
+			ok = true
+		}
+	}
+	return start, end, ok
+}
+
+// fetchSemanticTokensRange requests the semantic tokens of lines start to
+// end (1-indexed, inclusive) of b
+func (v *vimstate) fetchSemanticTokensRange(b *types.Buffer, st *bufSemanticTokens, start, end int) {
+	server := v.server
+	legend := v.semanticTokensCaps.legend
+	from, err := types.PointFromVim(b, start, 1)
+	if err != nil {
+		v.Logf("failed to determine start of semantic tokens range: %v", err)
+		return
+	}
+	to, err := endOfLinePoint(b, end)
+	if err != nil {
+		v.Logf("failed to determine end of semantic tokens range: %v", err)
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	st.cancel = cancel
+	version := b.Version
+	v.tomb.Go(func() error {
+		res, err := server.SemanticTokensRange(ctx, &protocol.SemanticTokensRangeParams{
+			TextDocument: b.ToTextDocumentIdentifier(),
+			Range: protocol.Range{
+				Start: from.ToPosition(),
+				End:   to.ToPosition(),
+			},
+		})
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+		v.Schedule(func(govim.Govim) error {
+			select {
+			case <-ctx.Done():
+				return nil
+			default:
+			}
+			st.cancel = nil
+			if err != nil {
+				v.semanticTokensCallFailed(err)
+				return nil
+			}
+			if nb, ok := v.buffers[b.Num]; !ok || nb != b || v.semanticTokens[b.Num] != st {
+				return nil
+			}
+			if b.Version != version {
+				// The buffer has changed in the meantime; fetch again
+				return v.updateSemanticHighlighting()
+			}
+			if st.version != version {
+				st.version = version
+				st.tokens = make(map[int][]semanticToken)
+				st.fetched = make(map[int]bool)
+				v.resetSemanticHighlighting(b, st)
+			}
+			var data []float64
+			if res != nil {
+				data = res.Data
+			}
+			tokens := decodeSemanticTokens(b, legend, data)
+			for l := start; l <= end; l++ {
+				st.fetched[l] = true
+				st.tokens[l] = tokens[l]
+			}
+			return v.renderSemanticTokens(b, st, v.Viewport())
+		})
+		return nil
+	})
+}
+
+// endOfLinePoint returns the point at the end of (1-indexed) line of b
+func endOfLinePoint(b *types.Buffer, line int) (types.Point, error) {
+	l, err := b.Line(line)
+	if err != nil {
+		return types.Point{}, err
+	}
+	return types.PointFromVim(b, line, len(l)+1)
+}
+
+// fetchSemanticTokens requests all the semantic tokens of b. If we have
+// tokens for a previous version of b, and gopls supports it, only the edits
+// to those tokens are requested.
+func (v *vimstate) fetchSemanticTokens(b *types.Buffer, st *bufSemanticTokens) {
+	server := v.server
+	caps := v.semanticTokensCaps
+	ctx, cancel := context.WithCancel(context.Background())
+	st.cancel = cancel
+	version := b.Version
+	prevResultID := st.resultID
+	prevData := st.data
+	if !caps.supportsEdits {
+		prevResultID = ""
+	}
+	v.tomb.Go(func() error {
+		var resultID string
+		var data []float64
+		var err error
+		if prevResultID != "" {
+			resultID, data, err = semanticTokensEdits(ctx, server, b, prevResultID, prevData)
+			if err != nil {
+				// Fallback to requesting all tokens
+				v.Logf("semanticTokensEdits call failed: %v", err)
+			}
+		}
+		if prevResultID == "" || err != nil {
+			var res *protocol.SemanticTokens
+			res, err = server.SemanticTokens(ctx, &protocol.SemanticTokensParams{
+				TextDocument: b.ToTextDocumentIdentifier(),
+			})
+			if res != nil {
+				resultID, data = res.ResultID, res.Data
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+		v.Schedule(func(govim.Govim) error {
+			select {
+			case <-ctx.Done():
+				return nil
+			default:
+			}
+			st.cancel = nil
+			if err != nil {
+				v.semanticTokensCallFailed(err)
+				return nil
+			}
+			if nb, ok := v.buffers[b.Num]; !ok || nb != b || v.semanticTokens[b.Num] != st {
+				return nil
+			}
+			if b.Version != version {
+				// The buffer has changed in the meantime; fetch again (the
+				// edits relative to these tokens)
+				st.resultID, st.data = resultID, data
+				v.fetchSemanticTokens(b, st)
+				return nil
+			}
+			st.version = version
+			st.resultID, st.data = resultID, data
+			st.tokens = decodeSemanticTokens(b, caps.legend, data)
+			st.fetched = nil
+			v.resetSemanticHighlighting(b, st)
+			return v.renderSemanticTokens(b, st, v.Viewport())
+		})
+		return nil
+	})
+}
+
+// semanticTokensCallFailed handles the failure err of a semantic tokens
+// request
+func (v *vimstate) semanticTokensCallFailed(err error) {
+	if jerr, ok := err.(*jsonrpc2.Error); ok && jerr.Code == jsonrpc2.CodeMethodNotFound {
+		v.Logf("gopls does not support semantic tokens; disabling semantic highlighting")
+		v.semanticTokensUnsupported = true
+		return
+	}
+	v.Logf("semanticTokens call failed: %v", err)
+}
+
+// resetSemanticHighlighting removes the highlighting of b, such that the
+// (new) tokens st.tokens can be rendered
+func (v *vimstate) resetSemanticHighlighting(b *types.Buffer, st *bufSemanticTokens) {
+	st.rendered = make(map[int]bool)
+	v.ChannelCall("prop_remove", struct {
+		ID    int `json:"id"`
+		BufNr int `json:"bufnr"`
+		All   int `json:"all"`
+	}{types.SemanticTextPropID, b.Num, 1})
+}
+
+// semanticTokensEdits requests from server the edits to the previous tokens
+// prevData of b, identified by prevResultID, and returns the updated tokens.
+// gopls may respond with all tokens instead of edits.
+func semanticTokensEdits(ctx context.Context, server protocol.Server, b *types.Buffer, prevResultID string, prevData []float64) (string, []float64, error) {
+	res, err := server.SemanticTokensEdits(ctx, &protocol.SemanticTokensEditsParams{
+		TextDocument:     b.ToTextDocumentIdentifier(),
+		PreviousResultID: prevResultID,
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	if res == nil {
+		return "", nil, fmt.Errorf("no semantic tokens returned")
+	}
+	// The result is either SemanticTokens or SemanticTokensEdits
+	byts, err := json.Marshal(res)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to marshal result: %v", err)
+	}
+	var either struct {
+		ResultID string                         `json:"resultId"`
+		Data     *[]float64                     `json:"data"`
+		Edits    *[]protocol.SemanticTokensEdit `json:"edits"`
+	}
+	if err := json.Unmarshal(byts, &either); err != nil {
+		return "", nil, fmt.Errorf("failed to unmarshal result: %v", err)
+	}
+	switch {
+	case either.Data != nil:
+		return either.ResultID, *either.Data, nil
+	case either.Edits != nil:
+		data, err := applySemanticTokensEdits(prevData, *either.Edits)
+		return either.ResultID, data, err
+	}
+	return "", nil, fmt.Errorf("unexpected semantic tokens result: %s", byts)
+}
+
+// applySemanticTokensEdits applies edits, each relative to the original
+// data, to a copy of data
+func applySemanticTokensEdits(data []float64, edits []protocol.SemanticTokensEdit) ([]float64, error) {
+	edits = append([]protocol.SemanticTokensEdit(nil), edits...)
+	sort.SliceStable(edits, func(i, j int) bool {
+		return edits[i].Start > edits[j].Start
+	})
+	res := append([]float64(nil), data...)
+	for _, e := range edits {
+		start, end := int(e.Start), int(e.Start+e.DeleteCount)
+		if start < 0 || end > len(res) || start > end {
+			return nil, fmt.Errorf("invalid semantic tokens edit %v for %v tokens", e, len(res))
+		}
+		res = append(res[:start], append(append([]float64(nil), e.Data...), res[end:]...)...)
+	}
+	return res, nil
+}
+
+// decodeSemanticTokens decodes the relative, UTF-16 based encoding of tokens
+// in data, per legend, into the tokens of b that we highlight
+func decodeSemanticTokens(b *types.Buffer, legend semanticTokensLegend, data []float64) map[int][]semanticToken {
+	res := make(map[int][]semanticToken)
+	var line, char float64
+	for i := 0; i+5 <= len(data); i += 5 {
+		if data[i] != 0 {
+			char = 0
+		}
+		line += data[i]
+		char += data[i+1]
+		length, typ, mods := data[i+2], int(data[i+3]), int(data[i+4])
+		if typ < 0 || typ >= len(legend.TokenTypes) {
+			continue
+		}
+		hi, ok := semanticTokenTypes[legend.TokenTypes[typ]]
+		if !ok {
+			continue
+		}
+		start, err := types.PointFromPosition(b, protocol.Position{Line: line, Character: char})
+		if err != nil {
+			continue
+		}
+		end, err := types.PointFromPosition(b, protocol.Position{Line: line, Character: char + length})
+		if err != nil {
+			continue
+		}
+		t := semanticToken{
+			line:   start.Line(),
+			col:    start.Col(),
+			endCol: end.Col(),
+			hi:     hi,
+		}
+		for j, m := range legend.TokenModifiers {
+			if mods&(1<<uint(j)) == 0 {
+				continue
+			}
+			if mhi, ok := semanticTokenModifiers[m]; ok {
+				t.mods = append(t.mods, mhi)
+			}
+		}
+		res[t.line] = append(res[t.line], t)
+	}
+	return res
+}
+
+// renderSemanticTokens highlights the tokens of those lines of b that are
+// visible in a window in vp, and have not already been highlighted
+func (v *vimstate) renderSemanticTokens(b *types.Buffer, st *bufSemanticTokens, vp govim.Viewport) error {
+	v.BatchStart()
+	defer v.BatchCancelIfNotEnded()
+	for _, w := range vp.Windows {
+		if w.BufNr != b.Num {
+			continue
+		}
+		for l := w.TopLine; l <= w.BotLine; l++ {
+			if st.rendered[l] {
+				continue
+			}
+			st.rendered[l] = true
+			for _, t := range st.tokens[l] {
+				for _, hi := range append([]config.Highlight{t.hi}, t.mods...) {
+					v.BatchAssertChannelCall(assertPropAdd, "prop_add", t.line, t.col,
+						propAddDict{string(hi), types.SemanticTextPropID, t.line, t.endCol, b.Num},
+					)
+				}
+			}
+		}
+	}
+	v.MustBatchEnd()
+	return nil
+}
+
+// removeAllSemanticHighlighting removes all semantic highlighting, and
+// forgets all cached semantic tokens
+func (v *vimstate) removeAllSemanticHighlighting() {
+	for _, st := range v.semanticTokens {
+		if st.cancel != nil {
+			st.cancel()
+		}
+	}
+	v.semanticTokens = make(map[int]*bufSemanticTokens)
+	v.removeTextProps(types.SemanticTextPropID)
+}
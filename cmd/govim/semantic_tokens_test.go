@@ -0,0 +1,155 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/govim/govim/cmd/govim/config"
+	"github.com/govim/govim/cmd/govim/internal/golang_org_x_tools/lsp/protocol"
+	"github.com/govim/govim/cmd/govim/internal/types"
+)
+
+var applySemanticTokensEditsTests = []struct {
+	name  string
+	data  []float64
+	edits []protocol.SemanticTokensEdit
+	want  []float64
+	err   bool
+}{
+	{
+		name: "no edits",
+		data: []float64{1, 2, 3},
+		want: []float64{1, 2, 3},
+	},
+	{
+		name: "replace",
+		data: []float64{1, 2, 3, 4, 5},
+		edits: []protocol.SemanticTokensEdit{
+			{Start: 1, DeleteCount: 2, Data: []float64{20, 30, 35}},
+		},
+		want: []float64{1, 20, 30, 35, 4, 5},
+	},
+	{
+		name: "edits relative to the original data",
+		data: []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10},
+		edits: []protocol.SemanticTokensEdit{
+			{Start: 7, Data: []float64{70}},
+			{Start: 2, DeleteCount: 3, Data: []float64{20, 21}},
+		},
+		want: []float64{1, 2, 20, 21, 6, 7, 70, 8, 9, 10},
+	},
+	{
+		name: "delete to end",
+		data: []float64{1, 2, 3, 4, 5},
+		edits: []protocol.SemanticTokensEdit{
+			{Start: 3, DeleteCount: 2},
+		},
+		want: []float64{1, 2, 3},
+	},
+	{
+		name: "edit beyond end",
+		data: []float64{1, 2, 3},
+		edits: []protocol.SemanticTokensEdit{
+			{Start: 2, DeleteCount: 2},
+		},
+		err: true,
+	},
+}
+
+func TestApplySemanticTokensEdits(t *testing.T) {
+	for _, tt := range applySemanticTokensEditsTests {
+		t.Run(tt.name, func(t *testing.T) {
+			orig := append([]float64(nil), tt.data...)
+			got, err := applySemanticTokensEdits(tt.data, tt.edits)
+			if tt.err {
+				if err == nil {
+					t.Fatalf("expected error; got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v; want %v", got, tt.want)
+			}
+			if !reflect.DeepEqual(tt.data, orig) {
+				t.Errorf("data modified: got %v; want %v", tt.data, orig)
+			}
+		})
+	}
+}
+
+func TestDecodeSemanticTokens(t *testing.T) {
+	b := types.NewBuffer(1, "/tmp/main.go", []byte("package main\n\nfunc f(x int, αβ string) {}\n"), true)
+	legend := semanticTokensLegend{
+		TokenTypes:     []string{"keyword", "function", "parameter"},
+		TokenModifiers: []string{"declaration", "readonly"},
+	}
+	data := []float64{
+		2, 5, 1, 1, 0, // f
+		0, 2, 1, 2, 1, // x, a declaration
+		0, 2, 3, 0, 0, // int, a keyword that we do not highlight
+		0, 5, 2, 2, 2, // αβ, readonly, with UTF-16 positions
+		0, 3, 6, 99, 0, // string, with a type not in the legend
+	}
+	want := map[int][]semanticToken{
+		3: {
+			{line: 3, col: 6, endCol: 7, hi: config.HighlightSemFunction},
+			{line: 3, col: 8, endCol: 9, hi: config.HighlightSemParameter},
+			{line: 3, col: 15, endCol: 19, hi: config.HighlightSemParameter, mods: []config.Highlight{config.HighlightSemReadonly}},
+		},
+	}
+	got := decodeSemanticTokens(b, legend, data)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v; want %v", got, want)
+	}
+}
+
+var parseSemanticTokensCapabilitiesTests = []struct {
+	name string
+	res  string
+	want *semanticTokensCapabilities
+}{
+	{
+		name: "unsupported",
+		res:  `{"capabilities":{"hoverProvider":true}}`,
+	},
+	{
+		name: "document only",
+		res:  `{"capabilities":{"semanticTokensProvider":{"legend":{"tokenTypes":["type"],"tokenModifiers":["readonly"]},"documentProvider":true}}}`,
+		want: &semanticTokensCapabilities{
+			legend: semanticTokensLegend{TokenTypes: []string{"type"}, TokenModifiers: []string{"readonly"}},
+		},
+	},
+	{
+		name: "range and edits",
+		res:  `{"capabilities":{"semanticTokensProvider":{"legend":{"tokenTypes":["type"],"tokenModifiers":[]},"rangeProvider":{},"documentProvider":{"edits":true}}}}`,
+		want: &semanticTokensCapabilities{
+			legend:        semanticTokensLegend{TokenTypes: []string{"type"}, TokenModifiers: []string{}},
+			supportsRange: true,
+			supportsEdits: true,
+		},
+	},
+	{
+		name: "no range",
+		res:  `{"capabilities":{"semanticTokensProvider":{"legend":{"tokenTypes":["type"]},"rangeProvider":false}}}`,
+		want: &semanticTokensCapabilities{
+			legend: semanticTokensLegend{TokenTypes: []string{"type"}},
+		},
+	},
+}
+
+func TestParseSemanticTokensCapabilities(t *testing.T) {
+	for _, tt := range parseSemanticTokensCapabilitiesTests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseSemanticTokensCapabilities([]byte(tt.res))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v; want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/govim/govim"
+	"github.com/govim/govim/cmd/govim/config"
+	"github.com/govim/govim/cmd/govim/internal/golang_org_x_tools/lsp/protocol"
+	"github.com/kr/pretty"
+)
+
+// ShowMessageRequest shows the message and actions in params as a popup menu
+// and blocks until the user picks one of the actions, dismisses the popup,
+// or config.Config.ShowMessageRequestTimeout elapses. In the latter two cases
+// the response is nil.
+func (g *govimplugin) ShowMessageRequest(ctxt context.Context, params *protocol.ShowMessageRequestParams) (*protocol.MessageActionItem, error) {
+	defer absorbShutdownErr()
+	g.logGoplsClientf("ShowMessageRequest callback: %v", pretty.Sprint(params))
+
+	if len(params.Actions) == 0 {
+		// Nothing to choose from, so this is no different to a
+		// window/showMessage notification
+		err := g.ShowMessage(ctxt, &protocol.ShowMessageParams{
+			Type:    params.Type,
+			Message: params.Message,
+		})
+		return nil, err
+	}
+
+	// Buffered so that the popup callback never blocks the Vim "thread", even
+	// if we have stopped waiting
+	selected := make(chan int, 1)
+	var popupID int
+	var timeout time.Duration
+	done, err := g.Schedule(func(govim.Govim) error {
+		v := g.vimstate
+		timeout = v.showMessageRequestTimeout()
+		popupID = v.showMessageRequestPopup(params)
+		v.messageRequestPopups[popupID] = selected
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to schedule ShowMessageRequest: %v", err)
+	}
+	select {
+	case <-done:
+	case <-g.inShutdown:
+		return nil, govim.ErrShuttingDown
+	}
+
+	var timer <-chan time.Time
+	if timeout > 0 {
+		t := time.NewTimer(timeout)
+		defer t.Stop()
+		timer = t.C
+	}
+
+	var res *protocol.MessageActionItem
+	select {
+	case sel := <-selected:
+		if sel >= 1 && sel <= len(params.Actions) {
+			res = &params.Actions[sel-1]
+		}
+	case <-ctxt.Done():
+		g.closeMessageRequestPopup(popupID)
+	case <-timer:
+		g.closeMessageRequestPopup(popupID)
+	case <-g.inShutdown:
+		return nil, govim.ErrShuttingDown
+	}
+	g.logGoplsClientf("ShowMessageRequest response: %v", pretty.Sprint(res))
+	return res, nil
+}
+
+// defaultShowMessageRequestTimeout is the timeout used for
+// window/showMessageRequest popups when config.Config.ShowMessageRequestTimeout
+// is not a valid duration. Whilst a popup is open, later callbacks from gopls
+// are blocked, hence we never fall back to waiting indefinitely.
+const defaultShowMessageRequestTimeout = 30 * time.Second
+
+// showMessageRequestTimeout returns the timeout for window/showMessageRequest
+// popups. Zero means no timeout.
+func (v *vimstate) showMessageRequestTimeout() time.Duration {
+	if v.config.ShowMessageRequestTimeout == nil {
+		return defaultShowMessageRequestTimeout
+	}
+	d, err := time.ParseDuration(*v.config.ShowMessageRequestTimeout)
+	if err == nil && d < 0 {
+		err = fmt.Errorf("negative duration")
+	}
+	if err != nil {
+		v.Logf("invalid ShowMessageRequestTimeout %q: %v; using %v", *v.config.ShowMessageRequestTimeout, err, defaultShowMessageRequestTimeout)
+		return defaultShowMessageRequestTimeout
+	}
+	return d
+}
+
+// showMessageRequestPopup creates a popup menu of the actions in params, with
+// the message as the title, and returns the ID of the popup. The selection is
+// reported via config.FunctionPopupSelection
+func (v *vimstate) showMessageRequestPopup(params *protocol.ShowMessageRequestParams) int {
+	var hl string
+	switch params.Type {
+	case protocol.Error:
+		hl = "ErrorMsg"
+	case protocol.Warning:
+		hl = "WarningMsg"
+	}
+	titles := make([]string, len(params.Actions))
+	for i, a := range params.Actions {
+		titles[i] = a.Title
+	}
+	opts := map[string]interface{}{
+		"title":      " " + strings.Join(strings.Fields(params.Message), " ") + " ",
+		"padding":    []int{0, 1, 0, 1},
+		"border":     []int{},
+		"cursorline": 1,
+		"wrap":       false,
+		"mapping":    0,
+		"drag":       1,
+		"filter":     "popup_filter_menu",
+		"callback":   "GOVIM" + config.FunctionPopupSelection,
+	}
+	if hl != "" {
+		opts["borderhighlight"] = []string{hl}
+	}
+	return v.ParseInt(v.ChannelCall("popup_create", titles, opts))
+}
+
+// closeMessageRequestPopup closes the window/showMessageRequest popup with
+// the given ID, if it is still open. Closing the popup triggers the popup
+// callback, which forgets the popup.
+func (g *govimplugin) closeMessageRequestPopup(popupID int) {
+	g.Schedule(func(govim.Govim) error {
+		if _, ok := g.vimstate.messageRequestPopups[popupID]; ok {
+			g.ChannelCall("popup_close", popupID)
+		}
+		return nil
+	})
+}
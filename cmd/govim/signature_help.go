@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/govim/govim"
+	"github.com/govim/govim/cmd/govim/config"
+	"github.com/govim/govim/cmd/govim/internal/golang_org_x_tools/lsp/protocol"
+	"github.com/govim/govim/cmd/govim/internal/types"
+)
+
+const exprSignatureHelpPos = `{"bufnr": bufnr(""), "line": line("."), "col": col("."), "screenpos": screenpos(win_getid(), line("."), col("."))}`
+
+type signatureHelpPos struct {
+	BufNr     int `json:"bufnr"`
+	Line      int `json:"line"`
+	Col       int `json:"col"`
+	ScreenPos struct {
+		Row int `json:"row"`
+		Col int `json:"col"`
+	} `json:"screenpos"`
+}
+
+// signatureHelpCursorMoved is called as the cursor moves in insert mode. If
+// signature help is enabled, it asynchronously requests signature help at
+// the cursor position, updating (or closing) the signature help popup once
+// the results are in. Any in-flight request is cancelled first, so that we
+// only ever render the latest response.
+func (v *vimstate) signatureHelpCursorMoved(args ...json.RawMessage) error {
+	if v.config.SignatureHelp == nil || !*v.config.SignatureHelp {
+		return nil
+	}
+	var pos signatureHelpPos
+	v.Parse(args[0], &pos)
+	b, ok := v.buffers[pos.BufNr]
+	if !ok {
+		return nil
+	}
+	point, err := types.PointFromVim(b, pos.Line, pos.Col)
+	if err != nil {
+		return fmt.Errorf("failed to determine cursor position: %v", err)
+	}
+
+	if v.cancelSignatureHelp != nil {
+		v.cancelSignatureHelp()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	v.cancelSignatureHelp = cancel
+
+	params := &protocol.SignatureHelpParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: b.ToTextDocumentIdentifier(),
+			Position:     point.ToPosition(),
+		},
+	}
+	server := v.server
+	v.tomb.Go(func() error {
+		res, err := server.SignatureHelp(ctx, params)
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+		if err != nil {
+			// We are very likely simply not within a call expression
+			v.Logf("signatureHelp call failed: %v", err)
+			res = nil
+		}
+		v.Schedule(func(govim.Govim) error {
+			select {
+			case <-ctx.Done():
+				return nil
+			default:
+			}
+			v.cancelSignatureHelp = nil
+			return v.showSignatureHelp(pos, res)
+		})
+		return nil
+	})
+	return nil
+}
+
+// signatureHelpInsertLeave closes the signature help popup, if any, on
+// leaving insert mode
+func (v *vimstate) signatureHelpInsertLeave(args ...json.RawMessage) error {
+	if v.cancelSignatureHelp != nil {
+		v.cancelSignatureHelp()
+		v.cancelSignatureHelp = nil
+	}
+	v.closeSignatureHelp()
+	return nil
+}
+
+func (v *vimstate) closeSignatureHelp() {
+	if v.signatureHelpPopupID > 0 {
+		v.ChannelCall("popup_close", v.signatureHelpPopupID)
+		v.signatureHelpPopupID = 0
+	}
+}
+
+func (v *vimstate) showSignatureHelp(pos signatureHelpPos, res *protocol.SignatureHelp) error {
+	if res == nil || len(res.Signatures) == 0 {
+		v.closeSignatureHelp()
+		return nil
+	}
+	active := int(res.ActiveSignature)
+	if active < 0 || active >= len(res.Signatures) {
+		active = 0
+	}
+	sig := res.Signatures[active]
+
+	line := popupLine{
+		Text:  sig.Label,
+		Props: []popupProp{},
+	}
+	// gopls sends parameter labels as strings, rather than offsets into the
+	// signature label, so we need to find the active parameter ourselves.
+	// Searching from the end of the previous parameter ensures we don't
+	// match an earlier parameter with the same text.
+	from := strings.Index(sig.Label, "(") + 1
+	for i, p := range sig.Parameters {
+		off := strings.Index(sig.Label[from:], p.Label)
+		if off == -1 {
+			break
+		}
+		off += from
+		if i == int(res.ActiveParameter) {
+			line.Props = append(line.Props, popupProp{
+				Type:   string(config.HighlightSignatureActiveParameter),
+				Col:    off + 1,
+				Length: len(p.Label),
+			})
+			break
+		}
+		from = off + len(p.Label)
+	}
+	lines := []popupLine{line}
+
+	var opts map[string]interface{}
+	if userOpts := v.config.ExperimentalSignatureHelpPopupOptions; userOpts != nil {
+		var err error
+		opts, err = relativePopupOptions(*userOpts, pos.ScreenPos.Row, pos.ScreenPos.Col)
+		if err != nil {
+			return err
+		}
+	} else {
+		opts = map[string]interface{}{
+			"pos":     "botleft",
+			"line":    pos.ScreenPos.Row - 1,
+			"col":     pos.ScreenPos.Col,
+			"padding": []int{0, 1, 0, 1},
+			"wrap":    false,
+		}
+	}
+
+	if v.signatureHelpPopupID > 0 {
+		v.BatchStart()
+		v.BatchChannelCall("popup_settext", v.signatureHelpPopupID, lines)
+		v.BatchChannelCall("popup_setoptions", v.signatureHelpPopupID, opts)
+		v.MustBatchEnd()
+		return nil
+	}
+	v.signatureHelpPopupID = v.ParseInt(v.ChannelCall("popup_create", lines, opts))
+	return nil
+}
@@ -89,7 +89,7 @@ func (v *vimstate) updateSigns(force bool) error {
 	if !force && !work {
 		return nil
 	}
-	diags := *diagsRef
+	diags := v.filterDiagnostics(*diagsRef, v.config.DiagnosticsSignsSeverity)
 
 	// We do this by batching a removal of all govim signs then a placing of all
 	// signs.
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/govim/govim/cmd/govim/config"
+	"github.com/govim/govim/cmd/govim/internal/types"
+)
+
+// snippetStop is a tab stop (or placeholder) in the text of an LSP snippet
+type snippetStop struct {
+	// index is the number of the tab stop, i.e. n in $n or ${n:...}. $0 is
+	// the final tab stop
+	index int
+
+	// offset and length are the byte offset and length of the placeholder
+	// text. length is zero for a plain tab stop
+	offset int
+	length int
+}
+
+// parseSnippet parses the LSP snippet s, returning the plain text of the
+// snippet and its tab stops in the order in which they should be visited.
+// Only the subset of the snippet syntax used by gopls is supported: tab stops
+// and placeholders, with placeholders nested within a placeholder being
+// treated as plain text.
+func parseSnippet(s string) (string, []snippetStop) {
+	var sb strings.Builder
+	var stops []snippetStop
+	seen := make(map[int]bool)
+	addStop := func(stop snippetStop) {
+		// Linked (repeated) tab stops are not supported; visit only the first
+		if !seen[stop.index] {
+			seen[stop.index] = true
+			stops = append(stops, stop)
+		}
+	}
+	digits := func(i int) (int, int) {
+		n, j := 0, i
+		for ; j < len(s) && s[j] >= '0' && s[j] <= '9'; j++ {
+			n = n*10 + int(s[j]-'0')
+		}
+		return n, j
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s) && strings.IndexByte(`$}\`, s[i+1]) != -1:
+			i++
+			sb.WriteByte(s[i])
+		case c == '$' && i+1 < len(s) && s[i+1] >= '0' && s[i+1] <= '9':
+			n, j := digits(i + 1)
+			addStop(snippetStop{index: n, offset: sb.Len()})
+			i = j - 1
+		case c == '$' && i+2 < len(s) && s[i+1] == '{' && s[i+2] >= '0' && s[i+2] <= '9':
+			n, j := digits(i + 2)
+			if j < len(s) && s[j] == '}' {
+				addStop(snippetStop{index: n, offset: sb.Len()})
+				i = j
+				continue
+			}
+			if j >= len(s) || s[j] != ':' {
+				// Not valid snippet syntax; treat as plain text
+				sb.WriteByte(c)
+				continue
+			}
+			// Find the matching close brace
+			depth, k := 1, j+1
+		Brace:
+			for ; k < len(s); k++ {
+				switch s[k] {
+				case '\\':
+					k++
+				case '{':
+					depth++
+				case '}':
+					depth--
+					if depth == 0 {
+						break Brace
+					}
+				}
+			}
+			if depth != 0 {
+				sb.WriteByte(c)
+				continue
+			}
+			text, _ := parseSnippet(s[j+1 : k])
+			addStop(snippetStop{index: n, offset: sb.Len(), length: len(text)})
+			sb.WriteString(text)
+			i = k
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	text := sb.String()
+	if len(stops) > 0 && !seen[0] {
+		// The final tab stop defaults to the end of the snippet
+		stops = append(stops, snippetStop{index: 0, offset: len(text)})
+	}
+	sort.SliceStable(stops, func(i, j int) bool {
+		a, b := stops[i].index, stops[j].index
+		if a == 0 || b == 0 {
+			return b == 0 && a != 0
+		}
+		return a < b
+	})
+	return text, stops
+}
+
+// snippetSession is the state of an expanded snippet whose tab stops can be
+// visited via config.FunctionSnippetJump
+type snippetSession struct {
+	bufnr int
+
+	// ids are the text property IDs that mark the tab stops, in the order
+	// in which they are visited
+	ids []int
+
+	// current is the index in ids of the current tab stop
+	current int
+}
+
+// startSnippet marks the tab stops of a snippet that has been inserted at
+// (line, col) in b and moves to the first tab stop
+func (v *vimstate) startSnippet(b *types.Buffer, line, col int, stops []snippetStop) {
+	v.endSnippet()
+	if len(stops) == 0 {
+		return
+	}
+	s := &snippetSession{
+		bufnr: b.Num,
+	}
+	v.BatchStart()
+	defer v.BatchCancelIfNotEnded()
+	for i, stop := range stops {
+		id := types.SnippetTextPropID + i
+		v.BatchAssertChannelCall(assertPropAdd, "prop_add", line, col+stop.offset,
+			propAddDict{string(config.HighlightSnippetPlaceholder), id, line, col + stop.offset + stop.length, b.Num},
+		)
+		s.ids = append(s.ids, id)
+	}
+	v.MustBatchEnd()
+	v.snippet = s
+}
+
+// endSnippet forgets the current snippet session, if any, removing the
+// marks for its tab stops
+func (v *vimstate) endSnippet() {
+	s := v.snippet
+	if s == nil {
+		return
+	}
+	v.snippet = nil
+	if b, ok := v.buffers[s.bufnr]; !ok || !b.Loaded {
+		return
+	}
+	v.ChannelCall("prop_remove", struct {
+		Type  string `json:"type"`
+		BufNr int    `json:"bufnr"`
+		All   int    `json:"all"`
+	}{string(config.HighlightSnippetPlaceholder), s.bufnr, 1})
+}
+
+// snippetJump is the implementation of config.FunctionSnippetJump. It moves
+// to the next (direction 1) or previous (direction -1) tab stop of the
+// current snippet
+func (v *vimstate) snippetJump(args ...json.RawMessage) (interface{}, error) {
+	dir := v.ParseInt(args[0])
+	if dir != 1 && dir != -1 {
+		return nil, fmt.Errorf("invalid direction %v; must be 1 or -1", dir)
+	}
+	s := v.snippet
+	if s == nil {
+		return nil, nil
+	}
+	if v.ParseInt(v.ChannelExpr(`bufnr("")`)) != s.bufnr {
+		v.endSnippet()
+		return nil, nil
+	}
+	next := s.current + dir
+	if next < 0 {
+		next = 0
+	}
+	if next >= len(s.ids) {
+		v.endSnippet()
+		return nil, nil
+	}
+	s.current = next
+	return nil, v.gotoSnippetStop()
+}
+
+// gotoSnippetStop moves to the current tab stop of the current snippet,
+// selecting its placeholder text (if any) in Select mode so that typing
+// replaces it. A tab stop that no longer exists (because its text has been
+// deleted) is skipped. Visiting the final tab stop ends the session.
+func (v *vimstate) gotoSnippetStop() error {
+	s := v.snippet
+	var prop struct {
+		Lnum   int `json:"lnum"`
+		Col    int `json:"col"`
+		Length int `json:"length"`
+	}
+	for ; s.current < len(s.ids); s.current++ {
+		v.Parse(v.ChannelCall("prop_find", struct {
+			Type  string `json:"type"`
+			ID    int    `json:"id"`
+			BufNr int    `json:"bufnr"`
+			Lnum  int    `json:"lnum"`
+			Col   int    `json:"col"`
+		}{string(config.HighlightSnippetPlaceholder), s.ids[s.current], s.bufnr, 1, 1}, "f"), &prop)
+		if prop.Lnum > 0 {
+			break
+		}
+	}
+	if prop.Lnum == 0 {
+		v.endSnippet()
+		return nil
+	}
+	if s.current == len(s.ids)-1 {
+		v.endSnippet()
+	}
+	line := v.ParseString(v.ChannelCall("getline", prop.Lnum))
+	var keys string
+	switch {
+	case prop.Length > 0:
+		end := prop.Col - 1 + prop.Length
+		if end > len(line) {
+			end = len(line)
+		}
+		n := utf8.RuneCountInString(line[prop.Col-1 : end])
+		keys = fmt.Sprintf(`:call cursor(%v, %v)\<CR>v`, prop.Lnum, prop.Col)
+		if n > 1 {
+			keys += fmt.Sprintf("%vl", n-1)
+		}
+		keys += `\<C-g>`
+	case prop.Col > len(line) && len(line) > 0:
+		keys = fmt.Sprintf(`:call cursor(%v, %v)\<CR>a`, prop.Lnum, len(line))
+	default:
+		keys = fmt.Sprintf(`:call cursor(%v, %v)\<CR>i`, prop.Lnum, prop.Col)
+	}
+	v.ChannelExf(`call feedkeys("\<Esc>%v", "n")`, keys)
+	return nil
+}
@@ -3,8 +3,10 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"sort"
 	"strings"
+	"sync"
 
 	"github.com/govim/govim"
 	"github.com/govim/govim/cmd/govim/config"
@@ -29,6 +31,14 @@ const (
 	FunctionNonBatchCallInBatch config.Function = "NonBatchCallInBatch"
 	FunctionIgnoreErrorInBatch  config.Function = "IgnoreErrorInBatch"
 	FunctionShowMessagePopup    config.Function = config.InternalFunctionPrefix + "ShowMessagePopup"
+	FunctionApplyEdit           config.Function = config.InternalFunctionPrefix + "ApplyEdit"
+	FunctionShowMessageRequest  config.Function = config.InternalFunctionPrefix + "ShowMessageRequest"
+	FunctionGoToLocations       config.Function = config.InternalFunctionPrefix + "GoToLocations"
+	FunctionShowHoverMarkdown   config.Function = config.InternalFunctionPrefix + "ShowHoverMarkdown"
+	FunctionFakeCallHierarchy   config.Function = config.InternalFunctionPrefix + "FakeCallHierarchy"
+	FunctionSendProgress        config.Function = config.InternalFunctionPrefix + "SendProgress"
+	FunctionCrashGopls          config.Function = config.InternalFunctionPrefix + "CrashGopls"
+	FunctionFakeSemanticTokens  config.Function = config.InternalFunctionPrefix + "FakeSemanticTokens"
 )
 
 func (g *govimplugin) InitTestAPI() {
@@ -40,6 +50,14 @@ func (g *govimplugin) InitTestAPI() {
 	g.DefineCommand(string(CommandHello), g.vimstate.helloComm, govim.NArgsZeroOrOne)
 	g.DefineFunction(string(FunctionDumpPopups), []string{}, g.vimstate.dumpPopups)
 	g.DefineFunction(string(FunctionShowMessagePopup), []string{}, g.vimstate.showMessagePopup)
+	g.DefineFunction(string(FunctionShowMessageRequest), []string{}, g.vimstate.showMessageRequestFromVim)
+	g.DefineFunction(string(FunctionApplyEdit), []string{"edit"}, g.vimstate.applyEditFromVim)
+	g.DefineFunction(string(FunctionGoToLocations), []string{"locations"}, g.vimstate.goToLocationsFromVim)
+	g.DefineFunction(string(FunctionShowHoverMarkdown), []string{"markdown"}, g.vimstate.showHoverMarkdownFromVim)
+	g.DefineFunction(string(FunctionFakeCallHierarchy), []string{"calls"}, g.vimstate.fakeCallHierarchyFromVim)
+	g.DefineFunction(string(FunctionSendProgress), []string{"token", "value"}, g.vimstate.sendProgressFromVim)
+	g.DefineFunction(string(FunctionCrashGopls), []string{}, g.vimstate.crashGopls)
+	g.DefineFunction(string(FunctionFakeSemanticTokens), []string{"tokens"}, g.vimstate.fakeSemanticTokensFromVim)
 	g.DefineFunction(string(FunctionSimpleBatch), []string{}, g.vimstate.simpleBatch)
 	g.DefineFunction(string(FunctionCancelBatch), []string{}, g.vimstate.cancelBatch)
 	g.DefineFunction(string(FunctionBadBatch), []string{}, g.vimstate.badBatch)
@@ -90,6 +108,76 @@ func (v *vimstate) showMessagePopup(args ...json.RawMessage) (interface{}, error
 	return "", nil
 }
 
+// sendProgressFromVim simulates gopls sending a $/progress notification with
+// the given token and value. This allows progress to be tested whilst work is
+// in progress: real work done progress from gopls is over too quickly to
+// observe reliably.
+func (v *vimstate) sendProgressFromVim(args ...json.RawMessage) (interface{}, error) {
+	params := &protocol.ProgressParams{}
+	v.Parse(args[0], &params.Token)
+	v.Parse(args[1], &params.Value)
+	v.tomb.Go(func() error {
+		return v.Progress(context.Background(), params)
+	})
+	return "", nil
+}
+
+// showMessageRequestFromVim simulates a window/showMessageRequest request
+// from gopls. The response is logged.
+// crashGopls kills the current gopls, as if it had crashed. Because a test
+// that crashes gopls cannot then survive govim exiting, from this point on
+// fatal gopls errors are only logged. See goplsFatal
+func (v *vimstate) crashGopls(args ...json.RawMessage) (interface{}, error) {
+	if v.gopls == nil {
+		return nil, fmt.Errorf("gopls is not running")
+	}
+	v.goplsFatalLock.Lock()
+	v.goplsFatalLogOnly = true
+	v.goplsFatalLock.Unlock()
+	return nil, v.gopls.Kill()
+}
+
+func (v *vimstate) showMessageRequestFromVim(args ...json.RawMessage) (interface{}, error) {
+	v.tomb.Go(func() error {
+		params := &protocol.ShowMessageRequestParams{
+			Type:    protocol.Warning,
+			Message: "Do something?",
+			Actions: []protocol.MessageActionItem{
+				{Title: "Yes"},
+				{Title: "No"},
+			},
+		}
+		_, err := v.ShowMessageRequest(context.Background(), params)
+		return err
+	})
+	return "", nil
+}
+
+// applyEditFromVim simulates a workspace/applyEdit request from gopls. The
+// edit is applied asynchronously, exactly as it would be for such a request.
+func (v *vimstate) applyEditFromVim(args ...json.RawMessage) (interface{}, error) {
+	var params applyWorkspaceEditParams
+	v.Parse(args[0], &params.Edit)
+	v.tomb.Go(func() error {
+		_, err := v.applyEdit(&params)
+		return err
+	})
+	return "", nil
+}
+
+// goToLocationsFromVim simulates a go to command from the cursor position for
+// which gopls returns the given locations. This allows the handling of
+// multiple locations to be tested: gopls only ever returns a single location
+// for definitions and type definitions.
+func (v *vimstate) goToLocationsFromVim(args ...json.RawMessage) (interface{}, error) {
+	var locs []protocol.Location
+	v.Parse(args[0], &locs)
+	err := v.gotoLocations("location", govim.CommandFlags{}, nil, func(protocol.TextDocumentPositionParams) ([]protocol.Location, error) {
+		return locs, nil
+	})
+	return nil, err
+}
+
 func (v *vimstate) simpleBatch(args ...json.RawMessage) (interface{}, error) {
 	v.BatchStart()
 	defer v.BatchCancelIfNotEnded()
@@ -153,3 +241,159 @@ func (v *vimstate) ignoreErrorInBatch(args ...json.RawMessage) (interface{}, err
 	res := v.MustBatchEnd()
 	return res, nil
 }
+
+// showHoverMarkdownFromVim opens a hover popup at the cursor position with
+// the given Markdown contents. This allows the rendering of Markdown that
+// gopls does not return to be tested: gopls escapes emphasis and code spans
+// in doc comments.
+func (v *vimstate) showHoverMarkdownFromVim(args ...json.RawMessage) (interface{}, error) {
+	var md string
+	v.Parse(args[0], &md)
+	v.closeHoverPopup()
+	var pos struct {
+		Row int `json:"row"`
+		Col int `json:"col"`
+	}
+	v.Parse(v.ChannelExpr(`screenpos(win_getid(), line("."), col("."))`), &pos)
+	lines, links := renderMarkdown(md)
+	v.hoverLinks = links
+	opts := map[string]interface{}{
+		"mousemoved": "any",
+	}
+	return nil, v.openHoverPopup(lines, opts, nil, pos.Row, pos.Col)
+}
+
+// fakeCallHierarchyServer is a gopls server that responds to call hierarchy
+// requests with fixed results, keyed by the name of the item, and delegates
+// all other requests. This allows the call hierarchy window to be tested:
+// the version of gopls we test against does not support call hierarchies.
+type fakeCallHierarchyServer struct {
+	protocol.Server
+	prepare  []protocol.CallHierarchyItem
+	incoming map[string][]protocol.CallHierarchyIncomingCall
+	outgoing map[string][]protocol.CallHierarchyOutgoingCall
+}
+
+func (f fakeCallHierarchyServer) PrepareCallHierarchy(context.Context, *protocol.CallHierarchyPrepareParams) ([]protocol.CallHierarchyItem, error) {
+	return f.prepare, nil
+}
+
+func (f fakeCallHierarchyServer) IncomingCalls(ctx context.Context, params *protocol.CallHierarchyIncomingCallsParams) ([]protocol.CallHierarchyIncomingCall, error) {
+	return f.incoming[params.Item.Name], nil
+}
+
+func (f fakeCallHierarchyServer) OutgoingCalls(ctx context.Context, params *protocol.CallHierarchyOutgoingCallsParams) ([]protocol.CallHierarchyOutgoingCall, error) {
+	return f.outgoing[params.Item.Name], nil
+}
+
+// fakeCallHierarchyFromVim replaces the current gopls server with a
+// fakeCallHierarchyServer. The argument is of the form:
+//
+//     {"prepare": [item...], "incoming": {name: [call...]}, "outgoing": {name: [call...]}}
+func (v *vimstate) fakeCallHierarchyFromVim(args ...json.RawMessage) (interface{}, error) {
+	var calls struct {
+		Prepare  []protocol.CallHierarchyItem                    `json:"prepare"`
+		Incoming map[string][]protocol.CallHierarchyIncomingCall `json:"incoming"`
+		Outgoing map[string][]protocol.CallHierarchyOutgoingCall `json:"outgoing"`
+	}
+	v.Parse(args[0], &calls)
+	v.server = fakeCallHierarchyServer{
+		Server:   v.server,
+		prepare:  calls.Prepare,
+		incoming: calls.Incoming,
+		outgoing: calls.Outgoing,
+	}
+	return nil, nil
+}
+
+// fakeSemanticTokensServer is a gopls server that responds to semantic tokens
+// requests with queued results, logging each request, and delegates all other
+// requests. This allows semantic highlighting to be tested: the version of
+// gopls we test against does not support semantic tokens.
+type fakeSemanticTokensServer struct {
+	protocol.Server
+	v         *vimstate
+	responses *fakeSemanticTokensResponses
+}
+
+// fakeSemanticTokensResponses are the queued results of each kind of semantic
+// tokens request. They are used by fetches on other goroutines, hence mu
+type fakeSemanticTokensResponses struct {
+	mu    sync.Mutex
+	Full  []protocol.SemanticTokens      `json:"full"`
+	Range []protocol.SemanticTokens      `json:"range"`
+	Edits []protocol.SemanticTokensEdits `json:"edits"`
+}
+
+func (f fakeSemanticTokensServer) SemanticTokens(ctx context.Context, params *protocol.SemanticTokensParams) (*protocol.SemanticTokens, error) {
+	f.v.Logf("fake semantic tokens request")
+	f.responses.mu.Lock()
+	defer f.responses.mu.Unlock()
+	if len(f.responses.Full) == 0 {
+		return nil, fmt.Errorf("no more fake semantic tokens responses")
+	}
+	res := f.responses.Full[0]
+	f.responses.Full = f.responses.Full[1:]
+	return &res, nil
+}
+
+func (f fakeSemanticTokensServer) SemanticTokensRange(ctx context.Context, params *protocol.SemanticTokensRangeParams) (*protocol.SemanticTokens, error) {
+	f.v.Logf("fake semantic tokens range request for lines %v to %v", params.Range.Start.Line+1, params.Range.End.Line+1)
+	f.responses.mu.Lock()
+	defer f.responses.mu.Unlock()
+	if len(f.responses.Range) == 0 {
+		return nil, fmt.Errorf("no more fake semantic tokens range responses")
+	}
+	res := f.responses.Range[0]
+	f.responses.Range = f.responses.Range[1:]
+	return &res, nil
+}
+
+func (f fakeSemanticTokensServer) SemanticTokensEdits(ctx context.Context, params *protocol.SemanticTokensEditsParams) (interface{}, error) {
+	f.v.Logf("fake semantic tokens edits request for result %v", params.PreviousResultID)
+	f.responses.mu.Lock()
+	defer f.responses.mu.Unlock()
+	if len(f.responses.Edits) == 0 {
+		return nil, fmt.Errorf("no more fake semantic tokens edits responses")
+	}
+	res := f.responses.Edits[0]
+	f.responses.Edits = f.responses.Edits[1:]
+	return &res, nil
+}
+
+// fakeSemanticTokensFromVim replaces the current gopls server with a
+// fakeSemanticTokensServer, with the given semantic tokens capabilities,
+// forgetting any current semantic highlighting, and then updates the semantic
+// highlighting. The argument is of the form:
+//
+//     {"legend": legend, "supportsRange": bool, "supportsEdits": bool, "full": [tokens...], "range": [tokens...], "edits": [edits...]}
+func (v *vimstate) fakeSemanticTokensFromVim(args ...json.RawMessage) (interface{}, error) {
+	var fake struct {
+		Legend        semanticTokensLegend `json:"legend"`
+		SupportsRange bool                 `json:"supportsRange"`
+		SupportsEdits bool                 `json:"supportsEdits"`
+		fakeSemanticTokensResponses
+	}
+	v.Parse(args[0], &fake)
+	server := v.server
+	if f, ok := server.(fakeSemanticTokensServer); ok {
+		server = f.Server
+	}
+	v.removeAllSemanticHighlighting()
+	v.server = fakeSemanticTokensServer{
+		Server: server,
+		v:      v,
+		responses: &fakeSemanticTokensResponses{
+			Full:  fake.Full,
+			Range: fake.Range,
+			Edits: fake.Edits,
+		},
+	}
+	v.semanticTokensCaps = &semanticTokensCapabilities{
+		legend:        fake.Legend,
+		supportsRange: fake.SupportsRange,
+		supportsEdits: fake.SupportsEdits,
+	}
+	v.semanticTokensUnsupported = false
+	return nil, v.updateSemanticHighlighting()
+}
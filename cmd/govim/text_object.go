@@ -0,0 +1,227 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"strings"
+)
+
+func (v *vimstate) textObject(args ...json.RawMessage) (interface{}, error) {
+	// GOVIMTextObject has the signature:
+	//
+	//     func GOVIMTextObject(object, extent string)
+	//
+	// and visually selects the text object, such that it can be used in
+	// Visual and Operator-pending mode mappings. See config.FunctionTextObject
+	// for details.
+	if len(args) != 2 {
+		return nil, fmt.Errorf("expected two string args")
+	}
+	var strargs []string
+	for i, a := range args {
+		var s string
+		if err := json.Unmarshal(a, &s); err != nil {
+			return nil, fmt.Errorf("failed to parse argument %v as a string: %v", i+1, err)
+		}
+		strargs = append(strargs, s)
+	}
+	object, extent := strargs[0], strargs[1]
+	switch extent {
+	case "inner", "around":
+	default:
+		return nil, fmt.Errorf("got unknown extent %q", extent)
+	}
+	inner := extent == "inner"
+
+	b, point, err := v.cursorPos()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current position: %v", err)
+	}
+
+	// Now ensure we block for the result of any in-flight parse. Note that in
+	// the case of syntax errors we work with the partial AST
+	if b.ASTWait == nil {
+		return nil, fmt.Errorf("got text object request before buffer had loaded?")
+	}
+	<-b.ASTWait
+	if b.AST == nil {
+		return nil, nil
+	}
+	tf := b.Fset.File(b.AST.Pos())
+	if tf == nil {
+		return nil, nil
+	}
+	to := textObjectFinder{
+		tf:       tf,
+		contents: b.Contents(),
+		offset:   point.Offset(),
+	}
+
+	var r selectionRange
+	var linewise, ok bool
+	switch object {
+	case "function":
+		r, linewise, ok = to.function(b.AST, inner)
+	case "comment":
+		r, linewise, ok = to.comment(b.AST, inner)
+	default:
+		return nil, fmt.Errorf("got unknown text object %q", object)
+	}
+	if !ok {
+		return nil, nil
+	}
+	mode := "v"
+	if linewise {
+		mode = "V"
+	}
+	return nil, v.visualSelect(b, r, mode)
+}
+
+// textObjectFinder finds text objects that enclose offset within a file
+type textObjectFinder struct {
+	tf       *token.File
+	contents []byte
+	offset   int
+}
+
+// function finds the innermost function declaration or literal that
+// encloses the cursor. For inner, the range is the body of the function,
+// without the braces.
+func (t textObjectFinder) function(f *ast.File, inner bool) (r selectionRange, linewise bool, ok bool) {
+	var found ast.Node
+	var start, end int
+	ast.Inspect(f, func(n ast.Node) bool {
+		var s token.Pos
+		switch n := n.(type) {
+		case *ast.FuncDecl:
+			s = n.Pos()
+			if n.Doc != nil {
+				s = n.Doc.Pos()
+			}
+		case *ast.FuncLit:
+			s = n.Pos()
+		default:
+			return true
+		}
+		if !s.IsValid() {
+			return true
+		}
+		ns, ne := t.tf.Offset(s), offsetOrFileSize(t.tf, n.End())
+		if ns <= t.offset && t.offset < ne {
+			// Later matches are nested within earlier matches
+			found, start, end = n, ns, ne
+		}
+		return true
+	})
+	if found == nil {
+		return
+	}
+	if !inner {
+		return t.lines(start, end)
+	}
+	var body *ast.BlockStmt
+	switch n := found.(type) {
+	case *ast.FuncDecl:
+		body = n.Body
+	case *ast.FuncLit:
+		body = n.Body
+	}
+	if body == nil || !body.Lbrace.IsValid() {
+		return
+	}
+	start = t.tf.Offset(body.Lbrace) + 1
+	end = offsetOrFileSize(t.tf, body.End())
+	if body.Rbrace.IsValid() {
+		end = t.tf.Offset(body.Rbrace)
+	}
+	return t.inner(start, end)
+}
+
+// comment finds the comment group that encloses the cursor, where the cursor
+// is also considered to be within a comment group if it is in the
+// indentation that precedes it. For inner, the range is the text of the
+// comments from after the opening marker of the first comment to before the
+// closing marker of the last; a Visual selection is contiguous, hence the
+// markers of any comments in between, e.g. the // of the second and
+// subsequent lines of a multi-line // comment, are included.
+func (t textObjectFinder) comment(f *ast.File, inner bool) (r selectionRange, linewise bool, ok bool) {
+	for _, cg := range f.Comments {
+		start, end := t.tf.Offset(cg.Pos()), offsetOrFileSize(t.tf, cg.End())
+		if ls := t.lineStart(start); t.blank(ls, start) {
+			start = ls
+		}
+		if start > t.offset || t.offset >= end {
+			continue
+		}
+		if !inner {
+			return t.lines(t.tf.Offset(cg.Pos()), end)
+		}
+		first, last := cg.List[0], cg.List[len(cg.List)-1]
+		start = t.tf.Offset(first.Pos()) + 2 // skip // or /*
+		end = offsetOrFileSize(t.tf, last.End())
+		if strings.HasPrefix(last.Text, "/*") {
+			end -= 2
+		}
+		return t.inner(start, end)
+	}
+	return
+}
+
+// lines returns the range [start, end) as a linewise range if it starts and
+// ends on line boundaries (give or take whitespace), and as a characterwise
+// range otherwise
+func (t textObjectFinder) lines(start, end int) (r selectionRange, linewise bool, ok bool) {
+	le := t.lineEnd(end)
+	if t.blank(t.lineStart(start), start) && t.blank(end, le) {
+		return selectionRange{start: start, end: le}, true, true
+	}
+	return selectionRange{start: start, end: end}, false, true
+}
+
+// inner returns the range [start, end), with whitespace trimmed. If the
+// range spans whole lines, as is the case for a multi-line function body,
+// the range is linewise and excludes the lines on which it starts and ends.
+func (t textObjectFinder) inner(start, end int) (r selectionRange, linewise bool, ok bool) {
+	if start >= end {
+		return
+	}
+	if t.blank(start, t.lineEnd(start)) && t.blank(t.lineStart(end), end) {
+		first := t.lineEnd(start) + 1
+		last := t.lineStart(end)
+		if first >= last {
+			return
+		}
+		return selectionRange{start: first, end: last}, true, true
+	}
+	text := t.contents[start:end]
+	trimmed := bytes.TrimLeft(text, " \t\n")
+	start += len(text) - len(trimmed)
+	end = start + len(bytes.TrimRight(trimmed, " \t\n"))
+	if start >= end {
+		return
+	}
+	return selectionRange{start: start, end: end}, false, true
+}
+
+// lineStart returns the offset of the start of the line that contains
+// offset
+func (t textObjectFinder) lineStart(offset int) int {
+	return bytes.LastIndexByte(t.contents[:offset], '\n') + 1
+}
+
+// lineEnd returns the offset of the newline that ends the line containing
+// offset, or the length of the contents if there is no such newline
+func (t textObjectFinder) lineEnd(offset int) int {
+	if i := bytes.IndexByte(t.contents[offset:], '\n'); i != -1 {
+		return offset + i
+	}
+	return len(t.contents)
+}
+
+// blank reports whether the contents [start, end) are all whitespace
+func (t textObjectFinder) blank(start, end int) bool {
+	return len(bytes.TrimSpace(t.contents[start:end])) == 0
+}
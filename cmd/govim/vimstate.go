@@ -49,6 +49,49 @@ type vimstate struct {
 	// popupWinId is the id of the window currently being used for a hover-based popup
 	popupWinId int
 
+	// inlineDiagnosticsHidden is the cursor line in insert mode, on which
+	// inline diagnostics are hidden (see config.Config.InlineDiagnostics)
+	inlineDiagnosticsHidden inlineDiagnosticsLine
+
+	// diagnosticsFilterOff is set when the filtering of diagnostics has been
+	// turned off via config.CommandDiagnosticsFilter
+	diagnosticsFilterOff bool
+
+	// hoverLinks are the links listed in the footer of the most recent hover
+	// popup, as followed by config.CommandHoverLink
+	hoverLinks []string
+
+	// progressPopupID is the id of the progress popup, if shown
+	progressPopupID int
+
+	// signatureHelpPopupID is the id of the signature help popup, if shown
+	signatureHelpPopupID int
+
+	// cancelCompletionResolve cancels the in-flight completionItem/resolve
+	// request, if any. It must only be used on the Vim "thread"
+	cancelCompletionResolve context.CancelFunc
+
+	// completionResolveUnsupported is set when gopls reports that it does
+	// not implement completionItem/resolve. It is reset when gopls is
+	// restarted
+	completionResolveUnsupported bool
+
+	// asyncCompletion is the current asynchronous completion (see
+	// config.Config.CompletionAsync), if any. It must only be used on the Vim
+	// "thread"
+	asyncCompletion *asyncCompletion
+
+	// asyncCompletionSeq is used to create a unique work done progress token
+	// for each asynchronous completion request
+	asyncCompletionSeq int
+
+	// snippet is the current snippet session, if any
+	snippet *snippetSession
+
+	// cancelSignatureHelp cancels the in-flight signatureHelp request, if any.
+	// It must only be used on the Vim "thread"
+	cancelSignatureHelp context.CancelFunc
+
 	// currBatch represents the batch we are collecting
 	currBatch *batch
 
@@ -63,8 +106,58 @@ type vimstate struct {
 	// codeAction call.
 	suggestedFixesPopups map[int][]protocol.WorkspaceEdit
 
-	// working directory (when govim was started)
-	// TODO: handle changes to current working directory during runtime
+	// messageRequestPopups are the currently open window/showMessageRequest
+	// popups, keyed by popup ID. The selection made in the popup is sent on
+	// the corresponding channel
+	messageRequestPopups map[int]chan int
+
+	// symbolPopup is the currently open workspace symbol picker popup, if any
+	symbolPopup *symbolPopup
+
+	// locationPopup is the currently open popup of target locations for one
+	// of the go to commands, if any
+	locationPopup *locationPopup
+
+	// outline is the state of the document outline window, if open
+	outline *outline
+
+	// callTree is the state of the call hierarchy window, if open
+	callTree *callTree
+
+	// renamePreview is the state of the rename preview window, if open
+	renamePreview *renamePreview
+
+	// editHistory is the history of edits applied via
+	// applyMultiBufTextedits, for the purposes of config.CommandUndoEdit and
+	// config.CommandRedoEdit. editHistoryPos is the number of those edits that
+	// are currently applied
+	editHistory    []*editTransaction
+	editHistoryPos int
+
+	// editTransaction is the edit transaction in progress, if any (see
+	// startEditTransaction)
+	editTransaction *editTransaction
+
+	// codeLenses are the code lenses of buffers, keyed by buffer number
+	codeLenses map[int]*bufCodeLenses
+
+	// foldingRanges are the folding ranges of buffers, keyed by buffer number
+	foldingRanges map[int]*bufFoldingRanges
+
+	// semanticTokens are the semantic tokens of buffers, keyed by buffer
+	// number
+	semanticTokens map[int]*bufSemanticTokens
+
+	// selectionRanges are the ranges walked by FunctionExpandSelection and
+	// FunctionShrinkSelection, keyed by buffer number
+	selectionRanges map[int]*selectionRanges
+
+	// semanticTokensUnsupported is set when gopls reports that it does not
+	// implement semantic tokens. It is reset when gopls is restarted
+	semanticTokensUnsupported bool
+
+	// workingDirectory is the global current working directory of Vim,
+	// updated on DirChanged. It must only be accessed on the Vim thread
 	workingDirectory string
 
 	// currentReferences is the range of each LSP documentHighlights under the cursor
@@ -95,7 +188,9 @@ func (v *vimstate) setConfig(args ...json.RawMessage) (interface{}, error) {
 		if v.config.QuickfixAutoDiagnostics == nil || !*v.config.QuickfixAutoDiagnostics {
 			// QuickfixAutoDiagnostics is now not on
 			v.lastQuickFixDiagnostics = []quickfixEntry{}
-			if v.quickfixIsDiagnostics {
+			if v.diagnosticsInLocationLists() {
+				v.clearDiagnosticsLocationLists()
+			} else if v.quickfixIsDiagnostics {
 				v.ChannelCall("setqflist", v.lastQuickFixDiagnostics, "r")
 			}
 		} else {
@@ -106,6 +201,12 @@ func (v *vimstate) setConfig(args ...json.RawMessage) (interface{}, error) {
 		}
 	}
 
+	if v.config.DiagnosticsList != nil && preConfig.DiagnosticsList != nil && *v.config.DiagnosticsList != *preConfig.DiagnosticsList {
+		if err := v.setDiagnosticsList(); err != nil {
+			return nil, fmt.Errorf("failed to update diagnostics list: %v", err)
+		}
+	}
+
 	if !vimconfig.EqualBool(v.config.QuickfixSigns, preConfig.QuickfixSigns) {
 		if v.config.QuickfixSigns == nil || !*v.config.QuickfixSigns {
 			// QuickfixSigns is now not on - clear all signs
@@ -129,6 +230,16 @@ func (v *vimstate) setConfig(args ...json.RawMessage) (interface{}, error) {
 		}
 	}
 
+	if !vimconfig.EqualBool(v.config.InlineDiagnostics, preConfig.InlineDiagnostics) {
+		if v.config.InlineDiagnostics == nil || !*v.config.InlineDiagnostics {
+			v.removeInlineDiagnostics()
+		} else if !v.hasTextPropText {
+			v.Logf("InlineDiagnostics requires Vim 9.0.0121 or later; ignoring")
+		} else if err := v.updateInlineDiagnostics(true); err != nil {
+			return nil, fmt.Errorf("failed to update inline diagnostics: %v", err)
+		}
+	}
+
 	if !vimconfig.EqualBool(v.config.HighlightReferences, preConfig.HighlightReferences) {
 		if v.config.HighlightReferences == nil || !*v.config.HighlightReferences {
 			// HighlightReferences is now not on - remove existing text properties
@@ -140,12 +251,70 @@ func (v *vimstate) setConfig(args ...json.RawMessage) (interface{}, error) {
 		}
 	}
 
+	if !vimconfig.EqualBool(v.config.CodeLens, preConfig.CodeLens) {
+		if v.config.CodeLens == nil || !*v.config.CodeLens {
+			v.removeAllCodeLenses()
+		} else {
+			v.updateCodeLenses()
+		}
+	}
+
+	if !vimconfig.EqualBool(v.config.FoldingRanges, preConfig.FoldingRanges) {
+		if v.config.FoldingRanges == nil || !*v.config.FoldingRanges {
+			v.removeAllFoldingRanges()
+		} else {
+			for _, b := range v.buffers {
+				v.updateFoldingRanges(b)
+			}
+		}
+	} else if !vimconfig.EqualFoldingRangeKinds(v.config.FoldingRangeKinds, preConfig.FoldingRangeKinds) {
+		if err := v.rerenderFoldingRanges(); err != nil {
+			return nil, fmt.Errorf("failed to update folding ranges: %v", err)
+		}
+	}
+
+	if !vimconfig.EqualSeverity(v.config.DiagnosticsQuickfixSeverity, preConfig.DiagnosticsQuickfixSeverity) ||
+		!vimconfig.EqualSeverity(v.config.DiagnosticsSignsSeverity, preConfig.DiagnosticsSignsSeverity) ||
+		!vimconfig.EqualSeverity(v.config.DiagnosticsHighlightSeverity, preConfig.DiagnosticsHighlightSeverity) ||
+		!vimconfig.EqualSeverity(v.config.DiagnosticsInlineSeverity, preConfig.DiagnosticsInlineSeverity) ||
+		!vimconfig.EqualStrings(v.config.DiagnosticsIncludeSources, preConfig.DiagnosticsIncludeSources) ||
+		!vimconfig.EqualStrings(v.config.DiagnosticsExcludeSources, preConfig.DiagnosticsExcludeSources) ||
+		!vimconfig.EqualStrings(v.config.DiagnosticsExcludePaths, preConfig.DiagnosticsExcludePaths) {
+		if err := v.redisplayDiagnostics(); err != nil {
+			return nil, fmt.Errorf("failed to update diagnostics: %v", err)
+		}
+	}
+
+	semanticHighlightingChanged := !vimconfig.EqualBool(v.config.SemanticHighlighting, preConfig.SemanticHighlighting)
+	if semanticHighlightingChanged && !v.semanticHighlightingEnabled() {
+		v.removeAllSemanticHighlighting()
+	}
+
+	if v.config.CompletionAsync == nil || !*v.config.CompletionAsync {
+		v.cancelAsyncCompletion()
+	}
+
+	if !vimconfig.EqualBool(v.config.ProgressPopup, preConfig.ProgressPopup) {
+		if err := v.progressChanged(); err != nil {
+			return nil, fmt.Errorf("failed to update progress popup: %v", err)
+		}
+	}
+
 	// v.server will be nil when we are Init()-ing govim. The init process
 	// triggers a "manual" call of govim#config#Set() and hence this function
 	// gets called before we have even started gopls.
 	var err error
 	if v.server != nil {
+		if !vimconfig.EqualBool(v.config.CompletionSnippets, preConfig.CompletionSnippets) {
+			// Snippet support is a client capability, and so can only be
+			// changed by starting a new gopls
+			return nil, v.restartGopls()
+		}
 		err = v.server.DidChangeConfiguration(context.Background(), &protocol.DidChangeConfigurationParams{})
+		if err == nil && semanticHighlightingChanged {
+			// gopls only provides semantic tokens when configured to do so
+			err = v.updateSemanticHighlighting()
+		}
 	}
 
 	return nil, err
@@ -157,6 +326,23 @@ func (v *vimstate) popupSelection(args ...json.RawMessage) (interface{}, error)
 	v.Parse(args[0], &popupID)
 	v.Parse(args[1], &selection)
 
+	if sp := v.symbolPopup; sp != nil && sp.id == popupID {
+		v.symbolPopup = nil
+		sp.cancelQuery()
+		return nil, v.workspaceSymbolSelected(sp, selection)
+	}
+
+	if lp := v.locationPopup; lp != nil && lp.id == popupID {
+		v.locationPopup = nil
+		return nil, v.locationSelected(lp, selection)
+	}
+
+	if selected, ok := v.messageRequestPopups[popupID]; ok {
+		delete(v.messageRequestPopups, popupID)
+		selected <- selection
+		return nil, nil
+	}
+
 	var edits []protocol.WorkspaceEdit
 	var ok bool
 	if edits, ok = v.suggestedFixesPopups[popupID]; !ok {
@@ -188,9 +374,19 @@ func (v *vimstate) setUserBusy(args ...json.RawMessage) (interface{}, error) {
 		return nil, v.removeReferenceHighlight(&pos)
 	}
 
+	if err := v.outlineFollowCursor(&pos); err != nil {
+		return nil, err
+	}
+
 	if err := v.updateReferenceHighlight(false, &pos); err != nil {
 		return nil, err
 	}
 
+	v.updateCodeLenses()
+
+	if err := v.updateSemanticHighlighting(); err != nil {
+		return nil, err
+	}
+
 	return nil, v.handleDiagnosticsChanged()
 }
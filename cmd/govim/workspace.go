@@ -0,0 +1,238 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/govim/govim"
+	"github.com/govim/govim/cmd/govim/internal/golang_org_x_tools/lsp/protocol"
+	"github.com/govim/govim/cmd/govim/internal/golang_org_x_tools/span"
+	"github.com/kr/pretty"
+)
+
+// workspaceFolder is a directory that gopls treats as a workspace root
+type workspaceFolder struct {
+	// dir is the absolute path of the folder
+	dir string
+
+	// gomod is the path of the go.mod file of the module containing dir, or
+	// the empty string if dir is not within a module
+	gomod string
+}
+
+func (w workspaceFolder) protocol() protocol.WorkspaceFolder {
+	return protocol.WorkspaceFolder{
+		URI:  string(span.URIFromPath(w.dir)),
+		Name: w.dir,
+	}
+}
+
+// newWorkspaceFolder resolves dir, relative to the current working directory
+// if need be, into a workspace folder
+func (v *vimstate) newWorkspaceFolder(dir string) (workspaceFolder, error) {
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(v.workingDirectory, dir)
+	}
+	dir = filepath.Clean(dir)
+	fi, err := os.Stat(dir)
+	if err != nil {
+		return workspaceFolder{}, fmt.Errorf("failed to stat workspace folder: %v", err)
+	}
+	if !fi.IsDir() {
+		return workspaceFolder{}, fmt.Errorf("%v is not a directory", dir)
+	}
+	gomod, err := goModPath(dir)
+	if err != nil {
+		return workspaceFolder{}, fmt.Errorf("failed to derive go.mod path: %v", err)
+	}
+	return workspaceFolder{dir: dir, gomod: gomod}, nil
+}
+
+// WorkspaceFolders returns the current workspace folders. It is safe to call
+// from any goroutine.
+func (g *govimplugin) WorkspaceFolders(context.Context) ([]protocol.WorkspaceFolder, error) {
+	defer absorbShutdownErr()
+	g.workspaceLock.Lock()
+	defer g.workspaceLock.Unlock()
+	res := make([]protocol.WorkspaceFolder, len(g.workspaceFolders))
+	for i, w := range g.workspaceFolders {
+		res[i] = w.protocol()
+	}
+	g.logGoplsClientf("WorkspaceFolders response: %v", pretty.Sprint(res))
+	return res, nil
+}
+
+// workspaceFolderIndex returns the index of the workspace folder dir, or -1.
+// It must be called with g.workspaceLock held.
+func (g *govimplugin) workspaceFolderIndex(dir string) int {
+	for i, w := range g.workspaceFolders {
+		if w.dir == dir {
+			return i
+		}
+	}
+	return -1
+}
+
+// inWorkspace returns true if dir is within one of the workspace folders
+func (g *govimplugin) inWorkspace(dir string) bool {
+	g.workspaceLock.Lock()
+	defer g.workspaceLock.Unlock()
+	for _, w := range g.workspaceFolders {
+		if dir == w.dir || strings.HasPrefix(dir, w.dir+string(os.PathSeparator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// watchModule ensures there is a modWatcher for the module of w, if any. It
+// must be called with g.workspaceLock held.
+func (g *govimplugin) watchModule(w workspaceFolder) error {
+	if w.gomod == "" {
+		return nil
+	}
+	if _, ok := g.modWatchers[w.gomod]; ok {
+		return nil
+	}
+	mw, err := newModWatcher(g, w.gomod)
+	if err != nil {
+		return fmt.Errorf("failed to create modWatcher for %v: %v", w.gomod, err)
+	}
+	g.modWatchers[w.gomod] = mw
+	return nil
+}
+
+// unwatchModule closes the modWatcher for the module of w, unless that module
+// is shared by another workspace folder. It must be called with
+// g.workspaceLock held, after w has been removed from g.workspaceFolders.
+func (g *govimplugin) unwatchModule(w workspaceFolder) error {
+	mw, ok := g.modWatchers[w.gomod]
+	if !ok {
+		return nil
+	}
+	for _, o := range g.workspaceFolders {
+		if o.gomod == w.gomod {
+			return nil
+		}
+	}
+	delete(g.modWatchers, w.gomod)
+	if err := mw.close(); err != nil {
+		return fmt.Errorf("failed to close file watcher for %v: %v", w.gomod, err)
+	}
+	return nil
+}
+
+// addWorkspaceFolders adds the directories dirs as workspace folders,
+// ignoring any that are already workspace folders
+func (v *vimstate) addWorkspaceFolders(dirs ...string) error {
+	var added []workspaceFolder
+	for _, d := range dirs {
+		w, err := v.newWorkspaceFolder(d)
+		if err != nil {
+			return err
+		}
+		added = append(added, w)
+	}
+
+	var params protocol.DidChangeWorkspaceFoldersParams
+	err := func() error {
+		v.workspaceLock.Lock()
+		defer v.workspaceLock.Unlock()
+		for _, w := range added {
+			if v.workspaceFolderIndex(w.dir) != -1 {
+				continue
+			}
+			v.workspaceFolders = append(v.workspaceFolders, w)
+			params.Event.Added = append(params.Event.Added, w.protocol())
+			if err := v.watchModule(w); err != nil {
+				return err
+			}
+		}
+		return nil
+	}()
+	if err != nil || len(params.Event.Added) == 0 {
+		return err
+	}
+	if err := v.server.DidChangeWorkspaceFolders(context.Background(), &params); err != nil {
+		return fmt.Errorf("failed to call gopls.DidChangeWorkspaceFolders: %v", err)
+	}
+	return nil
+}
+
+// removeWorkspaceFolders removes the workspace folders dirs
+func (v *vimstate) removeWorkspaceFolders(dirs ...string) error {
+	var params protocol.DidChangeWorkspaceFoldersParams
+	err := func() error {
+		v.workspaceLock.Lock()
+		defer v.workspaceLock.Unlock()
+		for i, d := range dirs {
+			if !filepath.IsAbs(d) {
+				d = filepath.Join(v.workingDirectory, d)
+			}
+			dirs[i] = filepath.Clean(d)
+			if v.workspaceFolderIndex(dirs[i]) == -1 {
+				return fmt.Errorf("%v is not a workspace folder", dirs[i])
+			}
+		}
+		var removed []workspaceFolder
+		for _, d := range dirs {
+			i := v.workspaceFolderIndex(d)
+			if i == -1 {
+				// d was given more than once
+				continue
+			}
+			w := v.workspaceFolders[i]
+			v.workspaceFolders = append(v.workspaceFolders[:i], v.workspaceFolders[i+1:]...)
+			removed = append(removed, w)
+			params.Event.Removed = append(params.Event.Removed, w.protocol())
+		}
+		for _, w := range removed {
+			if err := v.unwatchModule(w); err != nil {
+				return err
+			}
+		}
+		return nil
+	}()
+	if err != nil {
+		return err
+	}
+	if err := v.server.DidChangeWorkspaceFolders(context.Background(), &params); err != nil {
+		return fmt.Errorf("failed to call gopls.DidChangeWorkspaceFolders: %v", err)
+	}
+	return nil
+}
+
+// workspaceAdd adds the directories given as arguments as workspace folders.
+// With no arguments, the current working directory is added.
+func (v *vimstate) workspaceAdd(flags govim.CommandFlags, args ...string) error {
+	if len(args) == 0 {
+		args = []string{v.workingDirectory}
+	}
+	return v.addWorkspaceFolders(args...)
+}
+
+// workspaceRemove removes the workspace folders given as arguments
+func (v *vimstate) workspaceRemove(flags govim.CommandFlags, args ...string) error {
+	return v.removeWorkspaceFolders(args...)
+}
+
+// dirChanged is called when the Vim current working directory changes. Only
+// the global working directory is tracked: a window- or tab-local directory
+// (:lcd or :tcd) leaves v.workingDirectory unchanged. If the new working
+// directory is not within any existing workspace folder, it is added as a
+// workspace folder.
+func (v *vimstate) dirChanged(args ...json.RawMessage) error {
+	dir := v.ParseString(args[0])
+	if dir == v.workingDirectory {
+		return nil
+	}
+	v.workingDirectory = dir
+	if v.inWorkspace(dir) {
+		return nil
+	}
+	return v.addWorkspaceFolders(dir)
+}
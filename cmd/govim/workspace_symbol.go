@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+
+	"github.com/govim/govim"
+	"github.com/govim/govim/cmd/govim/config"
+	"github.com/govim/govim/cmd/govim/internal/golang_org_x_tools/lsp/protocol"
+	"github.com/govim/govim/cmd/govim/internal/golang_org_x_tools/span"
+)
+
+// symbolPopup is the state of a workspace symbol picker popup
+type symbolPopup struct {
+	id    int
+	query string
+	mods  govim.CommModList
+
+	// symbols are the current results for query, in the order in which they
+	// are listed in the popup
+	symbols []protocol.SymbolInformation
+
+	// from is the location from which the picker was opened, or nil if the
+	// picker was not opened from a Go buffer
+	from *protocol.Location
+
+	// cancel cancels the in-flight request for the symbols matching query,
+	// if any. It must only be used on the Vim "thread"
+	cancel context.CancelFunc
+}
+
+func (v *vimstate) workspaceSymbol(flags govim.CommandFlags, args ...string) error {
+	if v.symbolPopup != nil {
+		v.symbolPopup.cancelQuery()
+		v.ChannelCall("popup_close", v.symbolPopup.id)
+		v.symbolPopup = nil
+	}
+	sp := &symbolPopup{
+		mods: flags.Mods,
+	}
+	if len(args) == 1 {
+		sp.query = args[0]
+	}
+	if b, pos, err := v.cursorPos(); err == nil {
+		sp.from = &protocol.Location{
+			URI: protocol.DocumentURI(b.URI()),
+			Range: protocol.Range{
+				Start: pos.ToPosition(),
+				End:   pos.ToPosition(),
+			},
+		}
+	}
+	syms, err := v.server.Symbol(context.Background(), &protocol.WorkspaceSymbolParams{
+		Query: sp.query,
+	})
+	if err != nil {
+		return fmt.Errorf("call to gopls.Symbol failed: %v", err)
+	}
+	sp.symbols = syms
+	lines := v.symbolLines(syms)
+	opts := map[string]interface{}{
+		"pos":        "center",
+		"minwidth":   60,
+		"maxheight":  20,
+		"drag":       1,
+		"mapping":    0,
+		"cursorline": 1,
+		"wrap":       false,
+		"title":      symbolPopupTitle(sp.query),
+		"filter":     "GOVIM_internal_WorkspaceSymbolFilter",
+		"callback":   "GOVIM" + config.FunctionPopupSelection,
+	}
+	sp.id = v.ParseInt(v.ChannelCall("popup_create", lines, opts))
+	v.ChannelCall("setwinvar", sp.id, "govim_symbol_query", sp.query)
+	v.symbolPopup = sp
+	return nil
+}
+
+// workspaceSymbolUpdate is called from the workspace symbol popup filter each
+// time the user changes the query. The symbols matching the query are
+// requested asynchronously, such that typing is not blocked on gopls; any
+// in-flight request for a previous query is cancelled first, so that we only
+// ever render the results for the latest query.
+func (v *vimstate) workspaceSymbolUpdate(args ...json.RawMessage) (interface{}, error) {
+	popupID := v.ParseInt(args[0])
+	sp := v.symbolPopup
+	if sp == nil || sp.id != popupID {
+		return nil, fmt.Errorf("couldn't find workspace symbol popup id: %d", popupID)
+	}
+	sp.query = v.ParseString(args[1])
+	sp.cancelQuery()
+	server := v.server
+	ctx, cancel := context.WithCancel(context.Background())
+	sp.cancel = cancel
+	params := &protocol.WorkspaceSymbolParams{
+		Query: sp.query,
+	}
+	v.tomb.Go(func() error {
+		syms, err := server.Symbol(ctx, params)
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+		v.Schedule(func(govim.Govim) error {
+			select {
+			case <-ctx.Done():
+				return nil
+			default:
+			}
+			sp.cancel = nil
+			if v.symbolPopup != sp {
+				return nil
+			}
+			if err != nil {
+				v.Logf("call to gopls.Symbol failed: %v", err)
+				return nil
+			}
+			sp.symbols = syms
+			v.BatchStart()
+			v.BatchChannelCall("popup_settext", sp.id, v.symbolLines(syms))
+			v.BatchChannelCall("popup_setoptions", sp.id, map[string]interface{}{
+				"title": symbolPopupTitle(params.Query),
+			})
+			v.BatchChannelCall("win_execute", sp.id, "call cursor(1, 1)")
+			v.MustBatchEnd()
+			return nil
+		})
+		return nil
+	})
+	return nil, nil
+}
+
+// cancelQuery cancels the in-flight request for the symbols matching the
+// query, if any
+func (sp *symbolPopup) cancelQuery() {
+	if sp.cancel != nil {
+		sp.cancel()
+		sp.cancel = nil
+	}
+}
+
+// symbolLines returns the popup lines that list syms
+func (v *vimstate) symbolLines(syms []protocol.SymbolInformation) []string {
+	// must be non-nil
+	lines := []string{}
+	for _, s := range syms {
+		fn := span.URI(s.Location.URI).Filename()
+		if rel, err := filepath.Rel(v.workingDirectory, fn); err == nil {
+			fn = rel
+		}
+		lines = append(lines, fmt.Sprintf("%v %v %v:%v", s.Name, s.Kind, fn, int(s.Location.Range.Start.Line)+1))
+	}
+	return lines
+}
+
+func symbolPopupTitle(query string) string {
+	return fmt.Sprintf("Symbols: %v", query)
+}
+
+// workspaceSymbolSelected handles the selection of the 1-indexed selection
+// from sp
+func (v *vimstate) workspaceSymbolSelected(sp *symbolPopup, selection int) error {
+	if selection < 1 || selection > len(sp.symbols) { // 0 = popup_close() called, -1 = ESC closed popup
+		return nil
+	}
+	loc := sp.symbols[selection-1].Location
+	if sp.from != nil {
+		v.pushJumpStack(*sp.from)
+	}
+	return v.loadLocation(sp.mods, loc)
+}
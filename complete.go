@@ -26,7 +26,17 @@ type CompleteItem struct {
 	Word     string `json:"word"`
 	Info     string `json:"info"`
 	Menu     string `json:"menu"`
+	Kind     string `json:"kind"`
 	UserData string `json:"user_data"`
+
+	// Dup is treated as a boolean by Vim: see :help complete-items
+	Dup int `json:"dup"`
+
+	// Empty is treated as a boolean by Vim: see :help complete-items
+	Empty int `json:"empty"`
+
+	// Equal is treated as a boolean by Vim: see :help complete-items
+	Equal int `json:"equal"`
 }
 
 type CompleteInfo struct {
@@ -106,6 +106,7 @@ const (
 	EventQuickFixCmdPost                   // QuickFixCmdPost
 	EventSessionLoadPost                   // SessionLoadPost
 	EventMenuPopup                         // MenuPopup
+	EventCompleteChanged                   // CompleteChanged
 	EventCompleteDone                      // CompleteDone
 	EventUser                              // User
 )
@@ -19,4 +19,14 @@ if GOVIMPluginStatus() == "initcomplete"
   nnoremap <buffer> <silent> [] :call GOVIMMotion("prev", "File.Decls.End()")<cr>
   nnoremap <buffer> <silent> ][ :call GOVIMMotion("next", "File.Decls.Pos()")<cr>
   nnoremap <buffer> <silent> ]] :call GOVIMMotion("next", "File.Decls.End()")<cr>
+
+  " Text objects
+  xnoremap <buffer> <silent> if :<C-u>call GOVIMTextObject("function", "inner")<cr>
+  onoremap <buffer> <silent> if :<C-u>call GOVIMTextObject("function", "inner")<cr>
+  xnoremap <buffer> <silent> af :<C-u>call GOVIMTextObject("function", "around")<cr>
+  onoremap <buffer> <silent> af :<C-u>call GOVIMTextObject("function", "around")<cr>
+  xnoremap <buffer> <silent> ic :<C-u>call GOVIMTextObject("comment", "inner")<cr>
+  onoremap <buffer> <silent> ic :<C-u>call GOVIMTextObject("comment", "inner")<cr>
+  xnoremap <buffer> <silent> ac :<C-u>call GOVIMTextObject("comment", "around")<cr>
+  onoremap <buffer> <silent> ac :<C-u>call GOVIMTextObject("comment", "around")<cr>
 endif
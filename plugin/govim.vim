@@ -204,6 +204,16 @@ function GOVIMPluginStatus(...)
   return s:govim_status
 endfunction
 
+" GOVIMFoldExpr is intended for use with foldmethod=expr, i.e.:
+"
+"   setlocal foldmethod=expr foldexpr=GOVIMFoldExpr()
+"
+" The fold levels are computed by govim from gopls folding ranges (see the
+" FoldingRanges config option) and cached per buffer, so this is cheap.
+function GOVIMFoldExpr()
+  return get(get(b:, "govim_fold_levels", []), v:lnum-1, 0)
+endfunction
+
 function s:userBusy(busy)
   if s:userBusy != a:busy
     let s:userBusy = a:busy
@@ -534,6 +544,26 @@ function GOVIM_internal_SuggestedFixesFilter(id, key)
     return popup_filter_menu(a:id, a:key)
 endfunc
 
+function GOVIM_internal_WorkspaceSymbolFilter(id, key)
+    " Printable characters and backspace edit the query, everything else is
+    " handled as per a regular popup menu
+    let l:query = getwinvar(a:id, "govim_symbol_query")
+    if a:key == "\<BS>"
+        let l:query = strcharpart(l:query, 0, strchars(l:query)-1)
+    elseif strchars(a:key) == 1 && a:key =~ '\p'
+        let l:query .= a:key
+    elseif a:key == "\<c-n>"
+        return popup_filter_menu(a:id, "j")
+    elseif a:key == "\<c-p>"
+        return popup_filter_menu(a:id, "k")
+    else
+        return popup_filter_menu(a:id, a:key)
+    endif
+    call setwinvar(a:id, "govim_symbol_query", l:query)
+    call GOVIM_internal_WorkspaceSymbolUpdate(a:id, l:query)
+    return 1
+endfunc
+
 " In case we are running in test mode
 if $GOVIM_DISABLE_USER_BUSY == "true"
   function GOVIM_test_SetUserBusy(busy)
@@ -4,6 +4,12 @@ function! GOVIMTest_getqflist(...)
   return map(call(function('getqflist'), a:000), function('s:addbufname'))
 endfunction
 
+" GOVIMTest_getloclist is a simpler wrapper around getloclist that
+" substitutes bufname for bufnr
+function! GOVIMTest_getloclist(...)
+  return map(call(function('getloclist'), a:000), function('s:addbufname'))
+endfunction
+
 " GOVIMTest_sign_getplaced is a simple wrapper around sign_getplaced that
 " substitutes bufname for bufnr
 function! GOVIMTest_sign_getplaced(...)